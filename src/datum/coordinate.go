@@ -0,0 +1,151 @@
+package datum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+)
+
+// Coordinate is a latitude, longitude and altitude tagged with the
+// datum it was measured on. It is converted to WGS84 once, when it is
+// made by NewCoordinate or unmarshaled, and Lat, Lon and Alt give that
+// position, so it can be measured against coordinates of any other
+// type. The zero value is the point 0°N 0°E on WGS84.
+type Coordinate struct {
+	latitude  float64
+	longitude float64
+	altitude  latlong.Length // Height above the ellipsoid of the datum
+	datum     *Datum         // nil meaning WGS84
+	wgs84     latlong.Coordinate
+}
+
+// NewCoordinate tags a latitude, longitude and altitude with the datum
+// they were measured on, nil meaning WGS84. Positions out of range are
+// rejected with a *latlong.RangeError.
+func NewCoordinate(latitude, longitude float64, altitude latlong.Length, d *Datum) (Coordinate, error) {
+	position := latlong.Coordinate{Latitude: latitude, Longitude: longitude, Altitude: altitude}
+	if err := position.Validate(); err != nil {
+		return Coordinate{}, err
+	}
+	if d == WGS84 {
+		d = nil
+	}
+	c := Coordinate{latitude: latitude, longitude: longitude, altitude: altitude, datum: d, wgs84: position}
+	if d != nil {
+		c.wgs84 = d.ToWGS84(position)
+	}
+	return c, nil
+}
+
+// Local gives the latitude, longitude and altitude of the coordinate on
+// its own datum
+func (c Coordinate) Local() latlong.Coordinate {
+	return latlong.Coordinate{Latitude: c.latitude, Longitude: c.longitude, Altitude: c.altitude}
+}
+
+// Datum the coordinate was measured on
+func (c Coordinate) Datum() *Datum {
+	if c.datum == nil {
+		return WGS84
+	}
+	return c.datum
+}
+
+// ToWGS84 gives the latitude, longitude and height of the coordinate on
+// WGS84
+func (c Coordinate) ToWGS84() latlong.Coordinate {
+	return c.wgs84
+}
+
+func (c *Coordinate) UnmarshalJSON(b []byte) error {
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+
+	// Check number of fields in JSON object. The Altitude field is optional.
+	_, hasAltitude := obj["Altitude"]
+	if len(obj) > 4 || (len(obj) == 4 && !hasAltitude) {
+		return errors.New(fmt.Sprintf("Too many fields for datum.Coordinate"))
+	}
+	if len(obj) < 3 {
+		return errors.New(fmt.Sprintf("Not enough fields for datum.Coordinate"))
+	}
+
+	// Check Latitude
+	if _, ok := obj["Latitude"]; !ok {
+		return errors.New("Missing field 'Latitude'")
+	}
+	if _, ok := obj["Latitude"].(float64); !ok {
+		return errors.New("Wrong type for field 'Latitude'")
+	}
+
+	// Check Longitude
+	if _, ok := obj["Longitude"]; !ok {
+		return errors.New("Missing field 'Longitude'")
+	}
+	if _, ok := obj["Longitude"].(float64); !ok {
+		return errors.New("Wrong type for field 'Longitude'")
+	}
+
+	// Check Altitude
+	var altitude float64
+	if hasAltitude {
+		var ok bool
+		if altitude, ok = obj["Altitude"].(float64); !ok {
+			return errors.New("Wrong type for field 'Altitude'")
+		}
+	}
+
+	// Check Datum
+	if _, ok := obj["Datum"]; !ok {
+		return errors.New("Missing field 'Datum'")
+	}
+	name, ok := obj["Datum"].(string)
+	if !ok {
+		return errors.New("Wrong type for field 'Datum'")
+	}
+	d, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	// Check ranges
+	parsed, err := NewCoordinate(obj["Latitude"].(float64), obj["Longitude"].(float64), latlong.Meters(altitude), d)
+	if err != nil {
+		return err
+	}
+
+	// All clear
+	*c = parsed
+	return nil
+}
+
+// MarshalJSON encodes the coordinate as an object with the fields
+// UnmarshalJSON requires, with the altitude in meters if it is not
+// zero. Only datums known to Lookup can be read back, so others, such
+// as those made with NewDatum, are an error.
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	d := c.Datum()
+	if known, err := Lookup(d.name); err != nil || known != d {
+		return nil, errors.New(fmt.Sprintf("Cannot marshal a datum.Coordinate on unregistered datum '%s'", d.name))
+	}
+	return json.Marshal(struct {
+		Latitude, Longitude float64
+		Altitude            float64 `json:",omitempty"`
+		Datum               string
+	}{c.latitude, c.longitude, c.altitude.Meters(), d.name})
+}
+
+func (c Coordinate) Lat() float64 {
+	return c.wgs84.Latitude
+}
+
+func (c Coordinate) Lon() float64 {
+	return c.wgs84.Longitude
+}
+
+func (c Coordinate) Alt() latlong.Length {
+	return c.wgs84.Altitude
+}
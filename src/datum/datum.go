@@ -0,0 +1,226 @@
+// Package datum converts positions between geodetic datums, so that
+// coordinates read from older maps and equipment on datums such as
+// NAD27, ED50 or OSGB36 can be measured against WGS84 positions
+//
+// A datum places a reference ellipsoid relative to the earth. The same
+// point has different latitudes and longitudes on different datums,
+// often by a hundred meters or more. Each datum here is described by
+// the seven parameter Helmert transform that takes its earth-centered,
+// earth-fixed (ECEF) coordinates to those of WGS84.
+//
+// Reference for datum transformations can be found here:
+//   - https://en.wikipedia.org/wiki/Geodetic_datum
+//   - https://en.wikipedia.org/wiki/Helmert_transformation
+//   - https://en.wikipedia.org/wiki/Molodensky_transformation
+package datum
+
+import (
+	"ecef"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+	"strings"
+)
+
+// Helmert holds the seven parameters of a similarity transform between
+// two ECEF frames, in the position vector convention (EPSG method 9606)
+type Helmert struct {
+	TX, TY, TZ float64 // Translation, in meters
+	RX, RY, RZ float64 // Rotation about each axis, in arc seconds
+	Scale      float64 // Scale correction, in parts per million
+}
+
+// Arc seconds in a radian
+const arcSeconds = 180 * 60 * 60 / math.Pi
+
+// matrix gives the rotation and scale of the transform, which is
+// applied before the translation
+func (h Helmert) matrix() [3][3]float64 {
+	s := 1 + h.Scale/1e6
+	rx, ry, rz := h.RX/arcSeconds, h.RY/arcSeconds, h.RZ/arcSeconds
+	return [3][3]float64{
+		{s, -s * rz, s * ry},
+		{s * rz, s, -s * rx},
+		{-s * ry, s * rx, s},
+	}
+}
+
+// Apply transforms an ECEF coordinate from the source frame of the
+// transform to its target frame
+func (h Helmert) Apply(c ecef.Coordinate) ecef.Coordinate {
+	m := h.matrix()
+	return ecef.Coordinate{
+		X: h.TX + m[0][0]*c.X + m[0][1]*c.Y + m[0][2]*c.Z,
+		Y: h.TY + m[1][0]*c.X + m[1][1]*c.Y + m[1][2]*c.Z,
+		Z: h.TZ + m[2][0]*c.X + m[2][1]*c.Y + m[2][2]*c.Z,
+	}
+}
+
+// Invert transforms an ECEF coordinate from the target frame of the
+// transform back to its source frame. It is the exact inverse of
+// Apply, rather than Apply with the parameters negated, which is only
+// close for small rotations.
+func (h Helmert) Invert(c ecef.Coordinate) ecef.Coordinate {
+	m := h.matrix()
+	v := [3]float64{c.X - h.TX, c.Y - h.TY, c.Z - h.TZ}
+
+	// Multiply by the inverse of the matrix: its adjugate, whose rows
+	// are cross products of its columns, over its determinant
+	var adj [3][3]float64
+	for i := 0; i < 3; i++ {
+		j, k := (i+1)%3, (i+2)%3
+		adj[i][0] = m[1][j]*m[2][k] - m[2][j]*m[1][k]
+		adj[i][1] = m[2][j]*m[0][k] - m[0][j]*m[2][k]
+		adj[i][2] = m[0][j]*m[1][k] - m[1][j]*m[0][k]
+	}
+	det := m[0][0]*adj[0][0] + m[1][0]*adj[0][1] + m[2][0]*adj[0][2]
+
+	var r [3]float64
+	for i := range r {
+		r[i] = (adj[i][0]*v[0] + adj[i][1]*v[1] + adj[i][2]*v[2]) / det
+	}
+	return ecef.Coordinate{X: r[0], Y: r[1], Z: r[2]}
+}
+
+// Datum is a geodetic datum: a reference ellipsoid, and the Helmert
+// transform from its ECEF frame to that of WGS84
+type Datum struct {
+	name      string
+	ellipsoid *latlong.Ellipsoid
+	toWGS84   Helmert
+}
+
+// Common datums
+var (
+	// World Geodetic System 1984, used by GPS
+	WGS84 = mustDatum("WGS84", latlong.WGS84, Helmert{})
+	// North American Datum 1983, within a couple of meters of WGS84
+	NAD83 = mustDatum("NAD83", latlong.GRS80, Helmert{})
+	// North American Datum 1927, mean for the contiguous United States
+	NAD27 = mustDatum("NAD27", latlong.Clarke1866, Helmert{TX: -8, TY: 160, TZ: 176})
+	// European Datum 1950, mean for western Europe
+	ED50 = mustDatum("ED50", latlong.International1924, Helmert{TX: -87, TY: -98, TZ: -121})
+	// Ordnance Survey of Great Britain 1936, used by the British
+	// National Grid
+	OSGB36 = mustDatum("OSGB36", latlong.Airy1830, Helmert{
+		TX: 446.448, TY: -125.157, TZ: 542.060,
+		RX: 0.1502, RY: 0.2470, RZ: 0.8421,
+		Scale: -20.4894,
+	})
+)
+
+// Datums known to Lookup, by lowercase name
+var datums = map[string]*Datum{}
+
+func init() {
+	for _, d := range []*Datum{WGS84, NAD83, NAD27, ED50, OSGB36} {
+		datums[strings.ToLower(d.name)] = d
+	}
+}
+
+// NewDatum creates a user-defined Datum on the given reference
+// ellipsoid, from the Helmert transform of its ECEF coordinates to
+// those of WGS84
+func NewDatum(name string, ell *latlong.Ellipsoid, toWGS84 Helmert) (*Datum, error) {
+	if ell == nil {
+		return nil, errors.New(fmt.Sprintf("Datum %s needs a reference ellipsoid", name))
+	}
+	for _, p := range []float64{toWGS84.TX, toWGS84.TY, toWGS84.TZ, toWGS84.RX, toWGS84.RY, toWGS84.RZ, toWGS84.Scale} {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			return nil, errors.New(fmt.Sprintf("Transform of datum %s to WGS84 must be finite", name))
+		}
+	}
+	return &Datum{name: name, ellipsoid: ell, toWGS84: toWGS84}, nil
+}
+
+func mustDatum(name string, ell *latlong.Ellipsoid, toWGS84 Helmert) *Datum {
+	d, err := NewDatum(name, ell, toWGS84)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Lookup finds one of the common datums by name, ignoring case
+func Lookup(name string) (*Datum, error) {
+	if d, ok := datums[strings.ToLower(name)]; ok {
+		return d, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown datum '%s'", name))
+}
+
+func (d *Datum) String() string {
+	return d.name
+}
+
+// Name of the datum
+func (d *Datum) Name() string {
+	return d.name
+}
+
+// Ellipsoid the datum is built on
+func (d *Datum) Ellipsoid() *latlong.Ellipsoid {
+	return d.ellipsoid
+}
+
+// ToWGS84Transform is the Helmert transform from the ECEF frame of the
+// datum to that of WGS84
+func (d *Datum) ToWGS84Transform() Helmert {
+	return d.toWGS84
+}
+
+// ToWGS84 converts a point on the datum, at its altitude if it is a
+// latlong.Altituder, to its latitude, longitude and height on WGS84
+func (d *Datum) ToWGS84(point latlong.LatLonger) latlong.Coordinate {
+	return Transform(point, d, WGS84)
+}
+
+// FromWGS84 converts a point on WGS84, at its altitude if it is a
+// latlong.Altituder, to its latitude, longitude and height on the
+// datum
+func (d *Datum) FromWGS84(point latlong.LatLonger) latlong.Coordinate {
+	return Transform(point, WGS84, d)
+}
+
+// Transform converts a point from one datum to another by the Helmert
+// transforms of both, passing through ECEF coordinates on WGS84
+func Transform(point latlong.LatLonger, from, to *Datum) latlong.Coordinate {
+	c := ecef.ToCoordinateOn(point, from.ellipsoid)
+	c = to.toWGS84.Invert(from.toWGS84.Apply(c))
+	return c.ToLatLongOn(to.ellipsoid)
+}
+
+// Molodensky converts a point from one datum to another with the
+// abridged Molodensky formulas, which shift latitude, longitude and
+// height directly without passing through ECEF coordinates. Only the
+// translations of the datums are used, so it suits datums defined by a
+// shift alone, such as NAD27 and ED50, to within a few meters. For
+// datums with rotations, such as OSGB36, use Transform.
+func Molodensky(point latlong.LatLonger, from, to *Datum) latlong.Coordinate {
+	dx := from.toWGS84.TX - to.toWGS84.TX
+	dy := from.toWGS84.TY - to.toWGS84.TY
+	dz := from.toWGS84.TZ - to.toWGS84.TZ
+
+	a, f := from.ellipsoid.SemiMajorAxis(), from.ellipsoid.Flattening()
+	e2 := from.ellipsoid.EccentricitySquared()
+	da := to.ellipsoid.SemiMajorAxis() - a
+	df := to.ellipsoid.Flattening() - f
+
+	lat, lon := point.Lat()*math.Pi/180, point.Lon()*math.Pi/180
+	sinLat, cosLat := math.Sincos(lat)
+	sinLon, cosLon := math.Sincos(lon)
+	w := math.Sqrt(1 - e2*sinLat*sinLat)
+	m := a * (1 - e2) / (w * w * w) // Meridional radius of curvature
+	n := a / w                      // Prime vertical radius of curvature
+
+	dLat := (-dx*sinLat*cosLon - dy*sinLat*sinLon + dz*cosLat + (a*df+f*da)*2*sinLat*cosLat) / m
+	dLon := (-dx*sinLon + dy*cosLon) / (n * cosLat)
+	dh := dx*cosLat*cosLon + dy*cosLat*sinLon + dz*sinLat + (a*df+f*da)*sinLat*sinLat - da
+
+	return latlong.Coordinate{
+		Latitude:  (lat + dLat) * 180 / math.Pi,
+		Longitude: (lon + dLon) * 180 / math.Pi,
+		Altitude:  latlong.Altitude(point) + latlong.Meters(dh),
+	}.Normalize()
+}
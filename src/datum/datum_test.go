@@ -0,0 +1,168 @@
+package datum
+
+import (
+	"encoding/json"
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between floating point values
+)
+
+// Check a known point on OSGB36, the Caister water tower from the
+// worked example of the Ordnance Survey, whose Helmert transform is
+// good to about 5 meters, and that WGS84 and NAD83 are left as they
+// are.
+func TestKnown(t *testing.T) {
+	tower := latlong.Coordinate{Latitude: 52.657570305556, Longitude: 1.717921583333}
+	want := latlong.Coordinate{Latitude: 52.658007805556, Longitude: 1.716051944444}
+	if d := latlong.WGS84.Distance(OSGB36.ToWGS84(tower), want).Meters(); d > 5 {
+		t.Errorf("Caister water tower is %g m from where it should be on WGS84", d)
+	}
+
+	point := latlong.Coordinate{Latitude: 40.5, Longitude: -105.25, Altitude: latlong.Meters(1500)}
+	for _, d := range []*Datum{WGS84, NAD83} {
+		got := d.ToWGS84(point)
+		if math.Abs(got.Latitude-point.Latitude) > closeEnough || math.Abs(got.Longitude-point.Longitude) > closeEnough {
+			t.Errorf("%v on %s is %v on WGS84", point, d, got)
+		}
+	}
+
+	for _, name := range []string{"wgs84", "NAD27", "Ed50", "osgb36"} {
+		if _, err := Lookup(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if d, err := Lookup("NAD-27"); err == nil {
+		t.Errorf("Lookup(NAD-27) found %s", d)
+	}
+	if _, err := NewDatum("Nowhere", nil, Helmert{}); err == nil {
+		t.Errorf("Datum without an ellipsoid was accepted")
+	}
+}
+
+// Generate 100,000 random points on each datum, convert them to WGS84
+// and back, and assert that we got something close enough to the
+// original. Where the datum is a shift alone, assert that the abridged
+// Molodensky formulas land within a few meters of the Helmert
+// transform.
+func TestRandPoints(t *testing.T) {
+	for _, d := range []*Datum{NAD27, ED50, OSGB36} {
+		for i := 0; i < 100000; i++ {
+			want := latlong.Coordinate{
+				Latitude:  -89 + rand.Float64()*178,
+				Longitude: -180 + rand.Float64()*360,
+				Altitude:  latlong.Meters(rand.Float64() * 10000),
+			}
+
+			wgs84 := d.ToWGS84(want)
+			got := d.FromWGS84(wgs84)
+			if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+				t.Fatalf("Difference in latitude (%g) outside of acceptable range (%g)", d, closeEnough)
+			}
+			if d := math.Abs(want.Longitude - got.Longitude); d > closeEnough {
+				t.Fatalf("Difference in longitude (%g) outside of acceptable range (%g)", d, closeEnough)
+			}
+			if d := math.Abs((want.Altitude - got.Altitude).Meters()); d > 0.001 {
+				t.Fatalf("Difference in altitude (%g m) outside of acceptable range", d)
+			}
+
+			if d == OSGB36 {
+				continue
+			}
+			molodensky := Molodensky(want, d, WGS84)
+			if dist := latlong.WGS84.Distance(wgs84, molodensky).Meters(); dist > 5 {
+				t.Fatalf("Molodensky put %v on %s %g m from %v", want, d, dist, wgs84)
+			}
+			if dh := math.Abs((wgs84.Altitude - molodensky.Altitude).Meters()); dh > 5 {
+				t.Fatalf("Molodensky put %v on %s %g m off in height", want, d, dh)
+			}
+		}
+	}
+}
+
+// Check that tagged coordinates unmarshal and marshal, and that their
+// position is given on WGS84.
+func TestMarshal(t *testing.T) {
+	var c Coordinate
+	if err := json.Unmarshal([]byte(`{"Latitude": 51.47788, "Longitude": -0.00147, "Datum": "osgb36"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Datum() != OSGB36 || c.Lat() == c.Local().Latitude || c.Lon() == c.Local().Longitude {
+		t.Errorf("Unmarshaled %+v, on WGS84 at %f, %f", c, c.Lat(), c.Lon())
+	}
+	if wgs84 := OSGB36.ToWGS84(c.Local()); c.ToWGS84() != wgs84 || c.Lat() != wgs84.Latitude || c.Lon() != wgs84.Longitude {
+		t.Errorf("Unmarshaled %+v, on WGS84 at %f, %f, wanted %v", c, c.Lat(), c.Lon(), wgs84)
+	}
+	b, err := json.Marshal(c)
+	if err != nil || string(b) != `{"Latitude":51.47788,"Longitude":-0.00147,"Datum":"OSGB36"}` {
+		t.Errorf("%+v marshaled as %s, %v", c, b, err)
+	}
+
+	bad := []string{
+		`{"Latitude": 51.47788, "Longitude": -0.00147}`,
+		`{"Latitude": 51.47788, "Longitude": -0.00147, "Datum": "Tokyo"}`,
+		`{"Latitude": 51.47788, "Longitude": -0.00147, "Datum": 36}`,
+		`{"Latitude": 91, "Longitude": -0.00147, "Datum": "OSGB36"}`,
+		`{"Latitude": 51.47788, "Longitude": -0.00147, "Height": 5, "Datum": "OSGB36"}`,
+	}
+	for _, s := range bad {
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			t.Errorf("Unmarshaling %s succeeded", s)
+		}
+	}
+}
+
+// Check that the zero value and coordinates with no datum are on WGS84,
+// that positions out of range are rejected, and that coordinates on
+// datums Lookup does not know, which could not be read back, are not
+// marshaled.
+func TestNewCoordinate(t *testing.T) {
+	var zero Coordinate
+	if zero.Datum() != WGS84 || zero.Lat() != 0 || zero.Lon() != 0 || zero.Alt() != 0 {
+		t.Errorf("Zero value is %v on %s", zero.ToWGS84(), zero.Datum())
+	}
+	if b, err := json.Marshal(zero); err != nil || string(b) != `{"Latitude":0,"Longitude":0,"Datum":"WGS84"}` {
+		t.Errorf("Zero value marshaled as %s, %v", b, err)
+	}
+
+	c, err := NewCoordinate(40.5, -105.25, latlong.Meters(1500), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Datum() != WGS84 || c.Lat() != 40.5 || c.Lon() != -105.25 || c.Alt() != latlong.Meters(1500) {
+		t.Errorf("Coordinate with no datum is %v on %s", c.ToWGS84(), c.Datum())
+	}
+	if onWGS84, err := NewCoordinate(40.5, -105.25, latlong.Meters(1500), WGS84); err != nil || onWGS84 != c {
+		t.Errorf("Coordinate on WGS84 is %+v, %v, wanted %+v", onWGS84, err, c)
+	}
+
+	if _, err := NewCoordinate(91, 0, 0, NAD27); err == nil {
+		t.Error("Made a coordinate at 91°N")
+	}
+	if _, err := NewCoordinate(math.NaN(), 0, 0, NAD27); err == nil {
+		t.Error("Made a coordinate at NaN°N")
+	}
+
+	custom, err := NewDatum("Custom", latlong.Clarke1866, Helmert{TX: -10, TY: 150, TZ: 180})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Not the registered datum, even under the same name
+	twin, err := NewDatum("NAD27", latlong.Clarke1866, Helmert{TX: -8, TY: 160, TZ: 176})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []*Datum{custom, twin} {
+		c, err := NewCoordinate(40.5, -105.25, 0, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := json.Marshal(c); err == nil {
+			t.Errorf("Coordinate on %s marshaled as %s", d, b)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package ecef converts between geodetic coordinates and earth-centered,
+// earth-fixed (ECEF) Cartesian coordinates, and on to east-north-up and
+// north-east-down coordinates in the plane tangent to the earth at an
+// origin
+//
+// The origin of an ECEF frame is the center of the earth. The X axis
+// points through latitude 0, longitude 0, the Y axis through latitude
+// 0, longitude 90 and the Z axis through the north pole.
+//
+// Reference for ECEF can be found here: https://en.wikipedia.org/wiki/ECEF
+package ecef
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Lowest height, in meters, that UnmarshalJSON accepts. Anything deeper
+// is not a plausible position, and is more likely an n-vector, which
+// shares the same field names.
+const minHeight = -100000
+
+// Coordinate represents a position in space by its distances in
+// meters along the axes of the WGS84 ECEF frame
+type Coordinate struct {
+	X, Y, Z float64
+}
+
+// ToLatLong converts an ECEF Coordinate to its geodetic latitude,
+// longitude and height above WGS84
+func (c *Coordinate) ToLatLong() latlong.Coordinate {
+	return c.ToLatLongOn(latlong.WGS84)
+}
+
+// ToLatLongOn converts an ECEF Coordinate to its geodetic latitude,
+// longitude and height above the given reference ellipsoid
+func (c *Coordinate) ToLatLongOn(ell *latlong.Ellipsoid) latlong.Coordinate {
+	a, b := ell.SemiMajorAxis(), ell.SemiMinorAxis()
+	e2 := ell.EccentricitySquared()
+	ep2 := e2 / (1 - e2) // Second eccentricity squared
+
+	// Bowring's method, iterating on the parametric latitude beta.
+	// Three rounds are good to well under a millimeter on the earth.
+	p := math.Hypot(c.X, c.Y)
+	beta := math.Atan2(c.Z, (1-ell.Flattening())*p)
+	var lat float64
+	for i := 0; i < 3; i++ {
+		sinBeta, cosBeta := math.Sincos(beta)
+		lat = math.Atan2(c.Z+ep2*b*sinBeta*sinBeta*sinBeta, p-e2*a*cosBeta*cosBeta*cosBeta)
+		beta = math.Atan2((1-ell.Flattening())*math.Sin(lat), math.Cos(lat))
+	}
+
+	sinLat, cosLat := math.Sincos(lat)
+	height := p*cosLat + c.Z*sinLat - a*math.Sqrt(1-e2*sinLat*sinLat)
+	return latlong.Coordinate{
+		Latitude:  deg(lat),
+		Longitude: deg(math.Atan2(c.Y, c.X)),
+		Altitude:  latlong.Meters(height),
+	}
+}
+
+// ToCoordinate converts a LatLonger, at its altitude if it is a
+// latlong.Altituder, to ECEF coordinates on WGS84
+func ToCoordinate(point latlong.LatLonger) Coordinate {
+	return ToCoordinateOn(point, latlong.WGS84)
+}
+
+// ToCoordinateOn converts a LatLonger, at its altitude if it is a
+// latlong.Altituder, to ECEF coordinates on the given reference
+// ellipsoid
+func ToCoordinateOn(point latlong.LatLonger, ell *latlong.Ellipsoid) Coordinate {
+	sinLat, cosLat := math.Sincos(rad(point.Lat()))
+	sinLon, cosLon := math.Sincos(rad(point.Lon()))
+	e2 := ell.EccentricitySquared()
+	h := latlong.Altitude(point).Meters()
+
+	n := ell.SemiMajorAxis() / math.Sqrt(1-e2*sinLat*sinLat) // Prime vertical radius of curvature
+	return Coordinate{
+		X: (n + h) * cosLat * cosLon,
+		Y: (n + h) * cosLat * sinLon,
+		Z: (n*(1-e2) + h) * sinLat,
+	}
+}
+
+// Convert angle in radians to angle in degrees
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Convert angle in degrees to angle in radians
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+
+func (c *Coordinate) UnmarshalJSON(b []byte) error {
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+
+	// Check number of fields in JSON object
+	if len(obj) > 3 {
+		return errors.New(fmt.Sprintf("Too many fields for ecef.Coordinate"))
+	}
+	if len(obj) < 3 {
+		return errors.New(fmt.Sprintf("Not enough fields for ecef.Coordinate"))
+	}
+
+	// Check X
+	if _, ok := obj["X"]; !ok {
+		return errors.New("Missing field 'X'")
+	}
+	if _, ok := obj["X"].(float64); !ok {
+		return errors.New("Wrong type for field 'X'")
+	}
+
+	// Check Y
+	if _, ok := obj["Y"]; !ok {
+		return errors.New("Missing field 'Y'")
+	}
+	if _, ok := obj["Y"].(float64); !ok {
+		return errors.New("Wrong type for field 'Y'")
+	}
+
+	// Check Z
+	if _, ok := obj["Z"]; !ok {
+		return errors.New("Missing field 'Z'")
+	}
+	if _, ok := obj["Z"].(float64); !ok {
+		return errors.New("Wrong type for field 'Z'")
+	}
+
+	// Check height
+	parsed := Coordinate{X: obj["X"].(float64), Y: obj["Y"].(float64), Z: obj["Z"].(float64)}
+	if h := parsed.ToLatLong().Altitude.Meters(); h < minHeight {
+		return errors.New(fmt.Sprintf("ecef.Coordinate is %.0f m below the ellipsoid", -h))
+	}
+
+	// All clear
+	*c = parsed
+	return nil
+}
+
+// MarshalJSON encodes the coordinate as an object with the three fields
+// UnmarshalJSON requires
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct{ X, Y, Z float64 }{c.X, c.Y, c.Z})
+}
+
+// MarshalText encodes the coordinate as its three components in meters
+// separated by spaces, e.g. "1334000.5 -4654000.25 4138000"
+func (c Coordinate) MarshalText() ([]byte, error) {
+	var b []byte
+	for i, v := range []float64{c.X, c.Y, c.Z} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("Cannot marshal an ecef.Coordinate that is not finite")
+		}
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = strconv.AppendFloat(b, v, 'f', -1, 64)
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes the format written by MarshalText
+func (c *Coordinate) UnmarshalText(b []byte) error {
+	fields := strings.Fields(string(b))
+	if len(fields) != 3 {
+		return errors.New("ecef.Coordinate needs three components")
+	}
+	var v [3]float64
+	for i, f := range fields {
+		var err error
+		if v[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return errors.New(fmt.Sprintf("Bad component '%s' for ecef.Coordinate", f))
+		}
+	}
+	c.X, c.Y, c.Z = v[0], v[1], v[2]
+	return nil
+}
+
+func (c Coordinate) Lat() float64 {
+	return c.ToLatLong().Latitude
+}
+
+func (c Coordinate) Lon() float64 {
+	return c.ToLatLong().Longitude
+}
+
+func (c Coordinate) Alt() latlong.Length {
+	return c.ToLatLong().Altitude
+}
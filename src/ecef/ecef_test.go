@@ -0,0 +1,166 @@
+package ecef
+
+import (
+	"encoding/json"
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between angles, in degrees
+	closeMeters = 0.000001   // Maximum difference between lengths, in meters
+)
+
+// Check points on the axes of the frame.
+func TestAxes(t *testing.T) {
+	a, b := latlong.WGS84.SemiMajorAxis(), latlong.WGS84.SemiMinorAxis()
+	cases := []struct {
+		point latlong.Coordinate
+		want  Coordinate
+	}{
+		{latlong.Coordinate{Latitude: 0, Longitude: 0}, Coordinate{X: a}},
+		{latlong.Coordinate{Latitude: 0, Longitude: 90}, Coordinate{Y: a}},
+		{latlong.Coordinate{Latitude: 0, Longitude: 180, Altitude: latlong.Meters(1000)}, Coordinate{X: -a - 1000}},
+		{latlong.Coordinate{Latitude: 90, Longitude: 0}, Coordinate{Z: b}},
+		{latlong.Coordinate{Latitude: -90, Longitude: 0, Altitude: latlong.Meters(-10)}, Coordinate{Z: -b + 10}},
+	}
+	for _, c := range cases {
+		got := ToCoordinate(c.point)
+		if math.Abs(got.X-c.want.X) > closeMeters || math.Abs(got.Y-c.want.Y) > closeMeters || math.Abs(got.Z-c.want.Z) > closeMeters {
+			t.Errorf("ToCoordinate(%v) was %v, wanted %v", c.point, got, c.want)
+		}
+	}
+}
+
+// Generate 100,000 random lat/long coordinates from below sea level to
+// geostationary orbit, convert them to ECEF, convert them back, and
+// assert that we got something close enough to the original.
+func TestRandPoints(t *testing.T) {
+	ellipsoids := []*latlong.Ellipsoid{latlong.WGS84, latlong.Clarke1866, latlong.Airy1830}
+	for i := 0; i < 100000; i++ {
+		want := latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+			Altitude:  latlong.Meters(math.Pow(10, rand.Float64()*7.6) - 500),
+		}
+		ell := ellipsoids[i%len(ellipsoids)]
+		c := ToCoordinateOn(want, ell)
+		got := c.ToLatLongOn(ell)
+
+		if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+			t.Fatalf("Difference in latitude of %v (%g) outside of acceptable range", want, d)
+		}
+		// Longitude is meaningless at the poles
+		if d := math.Abs(want.Longitude - got.Longitude); d > closeEnough && math.Abs(want.Latitude) < 89.9999 {
+			t.Fatalf("Difference in longitude of %v (%g) outside of acceptable range", want, d)
+		}
+		if d := math.Abs((want.Altitude - got.Altitude).Meters()); d > closeMeters*(1+want.Altitude.Meters()/1e6) {
+			t.Fatalf("Difference in altitude of %v (%g m) outside of acceptable range", want, d)
+		}
+	}
+}
+
+// Check that coordinates marshal and unmarshal exactly, and that
+// n-vectors are not mistaken for ECEF coordinates.
+func TestMarshal(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		want := ToCoordinate(latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+			Altitude:  latlong.Meters(rand.Float64() * 20000),
+		})
+
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Coordinate
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", b, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, b, got)
+		}
+
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = Coordinate{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", text, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, text, got)
+		}
+	}
+
+	bad := []string{
+		`{"X": 10.0, "Y": 20.0, "Z": 30.0}`,
+		`{"X": 6378137, "Y": 0}`,
+		`{"X": 6378137, "Y": 0, "Z": "0"}`,
+		`{"X": 6378137, "Y": 0, "Z": 0, "W": 0}`,
+	}
+	for _, s := range bad {
+		var c Coordinate
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			t.Errorf("Unmarshaling %s succeeded", s)
+		}
+	}
+}
+
+// Check the directions of the local axes.
+func TestLocalFrame(t *testing.T) {
+	base := latlong.Coordinate{Latitude: 40, Longitude: -80, Altitude: latlong.Meters(100)}
+	frame := NewLocalFrame(base)
+
+	above := frame.ENU(latlong.Coordinate{Latitude: 40, Longitude: -80, Altitude: latlong.Meters(1100)})
+	if math.Abs(above.East) > closeMeters || math.Abs(above.North) > closeMeters || math.Abs(above.Up-1000) > closeMeters {
+		t.Errorf("Point 1000 m above the base was at %+v", above)
+	}
+	if ned := frame.NED(latlong.Coordinate{Latitude: 40, Longitude: -80, Altitude: latlong.Meters(1100)}); ned.Down != -above.Up {
+		t.Errorf("Point 1000 m above the base was at %+v", ned)
+	}
+
+	// Over a kilometer the plane and the ellipsoid part by a few
+	// centimeters, so compare with the geodesic distance loosely
+	base.Altitude = 0
+	frame = NewLocalFrame(base)
+	north := latlong.WGS84.Destination(base, 0, latlong.Kilometers(1))
+	if p := frame.ENU(north); math.Abs(p.East) > closeMeters || math.Abs(p.North-1000) > 0.001 || p.Up > 0 || p.Up < -0.1 {
+		t.Errorf("Point 1 km north of the base was at %+v", p)
+	}
+	east := latlong.WGS84.Destination(base, 90, latlong.Kilometers(1))
+	if p := frame.ENU(east); math.Abs(p.East-1000) > 0.001 || math.Abs(p.North) > 0.01 || p.Up > 0 || p.Up < -0.1 {
+		t.Errorf("Point 1 km east of the base was at %+v", p)
+	}
+}
+
+// Generate 100,000 random bases and positions within 100 km of them,
+// convert the positions to latitude and longitude, convert them back,
+// and assert that we got something close enough to the original.
+func TestRandLocalFrame(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		base := latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+			Altitude:  latlong.Meters(rand.Float64() * 1000),
+		}
+		frame := NewLocalFrame(base)
+		want := ENU{
+			East:  rand.Float64()*200000 - 100000,
+			North: rand.Float64()*200000 - 100000,
+			Up:    rand.Float64()*20000 - 1000,
+		}
+
+		got := frame.ENU(frame.FromENU(want))
+		if math.Abs(got.East-want.East) > closeMeters || math.Abs(got.North-want.North) > closeMeters || math.Abs(got.Up-want.Up) > closeMeters {
+			t.Fatalf("%+v from %v came back as %+v", want, base, got)
+		}
+		if back := frame.NED(frame.FromNED(want.ToNED())); back.ToENU() != got {
+			t.Fatalf("%+v from %v came back as %+v in NED", want, base, back)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ecef
+
+import (
+	"latlong"
+	"math"
+)
+
+// ENU is a position in meters east, north and up from the origin of a
+// LocalFrame
+type ENU struct {
+	East, North, Up float64
+}
+
+// NED is a position in meters north, east and down from the origin of
+// a LocalFrame, the convention used by aircraft
+type NED struct {
+	North, East, Down float64
+}
+
+// ToNED gives the same position as north, east and down
+func (p ENU) ToNED() NED {
+	return NED{North: p.North, East: p.East, Down: -p.Up}
+}
+
+// ToENU gives the same position as east, north and up
+func (p NED) ToENU() ENU {
+	return ENU{East: p.East, North: p.North, Up: -p.Down}
+}
+
+// LocalFrame is the plane tangent to the WGS84 ellipsoid at an origin,
+// such as a base. Up is along the ellipsoid normal at the origin, so
+// points far from it fall below the plane as the earth curves away.
+//
+// Reference for local tangent planes can be found here:
+// https://en.wikipedia.org/wiki/Local_tangent_plane_coordinates
+type LocalFrame struct {
+	origin Coordinate
+
+	// Rotation from ECEF to ENU, one row per local axis
+	east, north, up [3]float64
+}
+
+// NewLocalFrame creates the local tangent plane at origin, at its
+// altitude if it is a latlong.Altituder
+func NewLocalFrame(origin latlong.LatLonger) *LocalFrame {
+	sinLat, cosLat := math.Sincos(rad(origin.Lat()))
+	sinLon, cosLon := math.Sincos(rad(origin.Lon()))
+	return &LocalFrame{
+		origin: ToCoordinate(origin),
+		east:   [3]float64{-sinLon, cosLon, 0},
+		north:  [3]float64{-sinLat * cosLon, -sinLat * sinLon, cosLat},
+		up:     [3]float64{cosLat * cosLon, cosLat * sinLon, sinLat},
+	}
+}
+
+// ENU finds point, at its altitude if it is a latlong.Altituder, in
+// meters east, north and up from the origin
+func (f *LocalFrame) ENU(point latlong.LatLonger) ENU {
+	c := ToCoordinate(point)
+	d := [3]float64{c.X - f.origin.X, c.Y - f.origin.Y, c.Z - f.origin.Z}
+	return ENU{East: dot(f.east, d), North: dot(f.north, d), Up: dot(f.up, d)}
+}
+
+// NED finds point, at its altitude if it is a latlong.Altituder, in
+// meters north, east and down from the origin
+func (f *LocalFrame) NED(point latlong.LatLonger) NED {
+	return f.ENU(point).ToNED()
+}
+
+// FromENU finds the latitude, longitude and altitude of a position
+// given in meters east, north and up from the origin
+func (f *LocalFrame) FromENU(p ENU) latlong.Coordinate {
+	c := Coordinate{
+		X: f.origin.X + p.East*f.east[0] + p.North*f.north[0] + p.Up*f.up[0],
+		Y: f.origin.Y + p.East*f.east[1] + p.North*f.north[1] + p.Up*f.up[1],
+		Z: f.origin.Z + p.East*f.east[2] + p.North*f.north[2] + p.Up*f.up[2],
+	}
+	return c.ToLatLong()
+}
+
+// FromNED finds the latitude, longitude and altitude of a position
+// given in meters north, east and down from the origin
+func (f *LocalFrame) FromNED(p NED) latlong.Coordinate {
+	return f.FromENU(p.ToENU())
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
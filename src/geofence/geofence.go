@@ -0,0 +1,195 @@
+// Package geofence evaluates trip trajectories against named regions
+// of the earth, such as restricted zones or reimbursable regions
+//
+// Fences are loaded from a JSON file, either as a list of fences:
+//
+//     [
+//         {"Name": "Base", "Kind": "reimbursable",
+//          "Polygon": [{"Latitude": 1, "Longitude": 2}, ...]},
+//         {"Name": "Lab", "Kind": "restricted",
+//          "Center": {"Latitude": 3, "Longitude": 4}, "Radius": 500}
+//     ]
+//
+// or as a GeoJSON FeatureCollection of Polygon features, and of Point
+// features with a "radius" property. The "name" and "kind" properties
+// of each feature name the fence. Radii are in meters.
+//
+// Reference for GeoJSON can be found here: https://tools.ietf.org/html/rfc7946
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"latlong"
+	"strings"
+)
+
+// Restricted is the Kind of fence that travelers must not enter
+const Restricted = "restricted"
+
+// Fence is a named region, either a polygon or a circle
+type Fence struct {
+	Name string
+	Kind string
+
+	// Boundary of a polygonal fence, nil for circular fences
+	Polygon latlong.Polygon
+
+	// Center and radius of a circular fence
+	Center latlong.Coordinate
+	Radius latlong.Length
+}
+
+// Contains reports whether point lies inside the fence
+func (f *Fence) Contains(point latlong.LatLonger) bool {
+	if f.Polygon != nil {
+		return f.Polygon.Contains(point)
+	}
+	return latlong.Distance(f.Center, point) <= f.Radius
+}
+
+// Load reads fences from a JSON or GeoJSON file
+func Load(fname string) ([]Fence, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse reads fences from JSON or GeoJSON
+func Parse(b []byte) ([]Fence, error) {
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "[") {
+		return parseFences(b)
+	}
+	return parseGeoJSON(b)
+}
+
+func parseFences(b []byte) ([]Fence, error) {
+	var raw []struct {
+		Name    string
+		Kind    string
+		Polygon []latlong.Coordinate
+		Center  *latlong.Coordinate
+		Radius  float64
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	fences := make([]Fence, len(raw))
+	for i, r := range raw {
+		f := Fence{Name: r.Name, Kind: r.Kind}
+		switch {
+		case len(r.Polygon) > 0 && r.Center != nil:
+			return nil, errors.New(fmt.Sprintf("Fence '%s' cannot be both a polygon and a circle", r.Name))
+		case len(r.Polygon) > 0:
+			for _, c := range r.Polygon {
+				f.Polygon = append(f.Polygon, c)
+			}
+		case r.Center != nil:
+			f.Center = *r.Center
+			f.Radius = latlong.Meters(r.Radius)
+		default:
+			return nil, errors.New(fmt.Sprintf("Fence '%s' needs a Polygon or a Center", r.Name))
+		}
+		if err := f.check(); err != nil {
+			return nil, err
+		}
+		fences[i] = f
+	}
+	return fences, nil
+}
+
+func parseGeoJSON(b []byte) ([]Fence, error) {
+	var raw struct {
+		Type     string
+		Features []struct {
+			Type       string
+			Properties map[string]interface{}
+			Geometry   struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Type != "FeatureCollection" {
+		return nil, errors.New("GeoJSON fences must be a FeatureCollection")
+	}
+
+	fences := make([]Fence, len(raw.Features))
+	for i, feature := range raw.Features {
+		f := Fence{}
+		f.Name, _ = feature.Properties["name"].(string)
+		f.Kind, _ = feature.Properties["kind"].(string)
+
+		switch feature.Geometry.Type {
+		case "Polygon":
+			// Only the outer ring bounds the fence, holes are not supported
+			var rings [][][]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &rings); err != nil {
+				return nil, err
+			}
+			if len(rings) == 0 {
+				return nil, errors.New(fmt.Sprintf("Fence '%s' has no outer ring", f.Name))
+			}
+			ring := rings[0]
+			// GeoJSON rings repeat their first position at the end
+			if n := len(ring); n > 1 && ring[0][0] == ring[n-1][0] && ring[0][1] == ring[n-1][1] {
+				ring = ring[:n-1]
+			}
+			for _, position := range ring {
+				if len(position) < 2 {
+					return nil, errors.New(fmt.Sprintf("Bad position in fence '%s'", f.Name))
+				}
+				f.Polygon = append(f.Polygon, latlong.Coordinate{Latitude: position[1], Longitude: position[0]})
+			}
+		case "Point":
+			var position []float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &position); err != nil {
+				return nil, err
+			}
+			if len(position) < 2 {
+				return nil, errors.New(fmt.Sprintf("Bad position in fence '%s'", f.Name))
+			}
+			radius, ok := feature.Properties["radius"].(float64)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Point fence '%s' needs a numeric 'radius' property", f.Name))
+			}
+			f.Center = latlong.Coordinate{Latitude: position[1], Longitude: position[0]}
+			f.Radius = latlong.Meters(radius)
+		default:
+			return nil, errors.New(fmt.Sprintf("Unsupported geometry '%s' for fence '%s'", feature.Geometry.Type, f.Name))
+		}
+		if err := f.check(); err != nil {
+			return nil, err
+		}
+		fences[i] = f
+	}
+	return fences, nil
+}
+
+// Check that a fence encloses a region and that its coordinates are
+// in range
+func (f *Fence) check() error {
+	if f.Polygon != nil && len(f.Polygon) < 3 {
+		return errors.New(fmt.Sprintf("Fence '%s' needs at least 3 vertices", f.Name))
+	}
+	if f.Polygon == nil && !(f.Radius > 0) {
+		return errors.New(fmt.Sprintf("Fence '%s' needs a positive radius", f.Name))
+	}
+	for _, v := range f.Polygon {
+		if err := (latlong.Coordinate{Latitude: v.Lat(), Longitude: v.Lon()}).Validate(); err != nil {
+			return errors.New(fmt.Sprintf("Fence '%s': %s", f.Name, err))
+		}
+	}
+	if err := f.Center.Validate(); err != nil {
+		return errors.New(fmt.Sprintf("Fence '%s': %s", f.Name, err))
+	}
+	return nil
+}
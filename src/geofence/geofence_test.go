@@ -0,0 +1,195 @@
+package geofence
+
+import (
+	"latlong"
+	"math"
+	"nvector"
+	"testing"
+)
+
+const (
+	closeEnough = 1.0 // Maximum difference between distances, in meters
+)
+
+var fenceJSON = `[
+	{"Name": "Base", "Kind": "reimbursable",
+	 "Polygon": [{"Latitude": -1, "Longitude": -1}, {"Latitude": -1, "Longitude": 1},
+	             {"Latitude": 1, "Longitude": 1}, {"Latitude": 1, "Longitude": -1}]},
+	{"Name": "Lab", "Kind": "restricted",
+	 "Center": {"Latitude": 0, "Longitude": 5}, "Radius": 50000}
+]`
+
+var fenceGeoJSON = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "properties": {"name": "Base", "kind": "reimbursable"},
+		 "geometry": {"type": "Polygon", "coordinates": [[[-1, -1], [1, -1], [1, 1], [-1, 1], [-1, -1]]]}},
+		{"type": "Feature", "properties": {"name": "Lab", "kind": "restricted", "radius": 50000},
+		 "geometry": {"type": "Point", "coordinates": [5, 0]}}
+	]
+}`
+
+// Fly along the equator out of the base and through the lab, checking
+// the events, visits and violations reported for fences loaded from
+// both JSON formats.
+func TestEvaluate(t *testing.T) {
+	trajectory := []latlong.LatLonger{
+		latlong.Coordinate{Latitude: 0, Longitude: 0},
+		latlong.Coordinate{Latitude: 0, Longitude: 2},
+		latlong.Coordinate{Latitude: 0, Longitude: 5},
+		latlong.Coordinate{Latitude: 0, Longitude: 8},
+	}
+	degree := latlong.Distance(latlong.Coordinate{}, latlong.Coordinate{Latitude: 0, Longitude: 1})
+
+	for _, source := range []string{fenceJSON, fenceGeoJSON} {
+		fences, err := Parse([]byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := Evaluate(fences, trajectory, latlong.Distance, nvector.Interpolate)
+
+		if len(report.Events) != 4 {
+			t.Fatalf("Got %d events, wanted 4", len(report.Events))
+		}
+		if e := report.Events[1]; e.Fence.Name != "Base" || e.Entered || e.Fix != 1 {
+			t.Errorf("Second event was %+v, wanted exit from Base at fix 1", e)
+		}
+		if len(report.Violations) != 1 || report.Violations[0].Fence.Name != "Lab" {
+			t.Errorf("Got violations %+v, wanted one entry into Lab", report.Violations)
+		}
+
+		if len(report.Visits) != 2 {
+			t.Fatalf("Got %d visits, wanted 2", len(report.Visits))
+		}
+		if d := math.Abs(float64(report.Visits[0].Distance - degree)); d > closeEnough {
+			t.Errorf("Distance inside Base was %f, wanted %f", report.Visits[0].Distance, degree)
+		}
+		if d := math.Abs(float64(report.Visits[1].Distance - latlong.Meters(100000))); d > closeEnough {
+			t.Errorf("Distance inside Lab was %f, wanted 100000", report.Visits[1].Distance)
+		}
+		if report.Visits[1].Fixes != 1 {
+			t.Errorf("Fixes inside Lab were %d, wanted 1", report.Visits[1].Fixes)
+		}
+	}
+}
+
+// Fly a single leg straight through a fence, with no fix inside it.
+func TestPassThrough(t *testing.T) {
+	fences, err := Parse([]byte(fenceJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trajectory := []latlong.LatLonger{
+		latlong.Coordinate{Latitude: 0, Longitude: 3},
+		latlong.Coordinate{Latitude: 0, Longitude: 7},
+	}
+
+	report := Evaluate(fences, trajectory, latlong.Distance, nvector.Interpolate)
+	if len(report.Visits) != 1 || len(report.Violations) != 1 || len(report.Events) != 2 {
+		t.Fatalf("Got %+v, wanted a single pass through Lab", report)
+	}
+	if d := math.Abs(float64(report.Visits[0].Distance - latlong.Meters(100000))); d > closeEnough {
+		t.Errorf("Distance inside Lab was %f, wanted 100000", report.Visits[0].Distance)
+	}
+}
+
+// A restricted fence shaped like a U, open to the north, whose prongs
+// each span a degree of longitude on the equator with a degree between
+// them
+var uJSON = `[{"Name": "U", "Kind": "restricted", "Polygon": [
+	{"Latitude": -2, "Longitude": 0}, {"Latitude": -2, "Longitude": 3},
+	{"Latitude": 1, "Longitude": 3}, {"Latitude": 1, "Longitude": 2},
+	{"Latitude": -1, "Longitude": 2}, {"Latitude": -1, "Longitude": 1},
+	{"Latitude": 1, "Longitude": 1}, {"Latitude": 1, "Longitude": 0}]}]`
+
+// Fly single legs across the gap of a concave fence, and check that
+// every exit and re-entry along them is reported and only the stretches
+// inside are counted.
+func TestConcave(t *testing.T) {
+	fences, err := Parse([]byte(uJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	degree := latlong.Distance(latlong.Coordinate{}, latlong.Coordinate{Latitude: 0, Longitude: 1})
+
+	// From one prong to the other, starting and ending inside
+	trajectory := []latlong.LatLonger{
+		latlong.Coordinate{Latitude: 0, Longitude: 0.5},
+		latlong.Coordinate{Latitude: 0, Longitude: 2.5},
+	}
+	report := Evaluate(fences, trajectory, latlong.Distance, nvector.Interpolate)
+	if len(report.Events) != 3 || report.Events[1].Entered || !report.Events[2].Entered || report.Events[2].Fix != 1 {
+		t.Errorf("Got events %+v, wanted entry at fix 0, then exit and entry at fix 1", report.Events)
+	}
+	if len(report.Visits) != 1 || report.Visits[0].Entries != 2 || len(report.Violations) != 2 {
+		t.Fatalf("Got %+v, wanted two entries into U", report)
+	}
+	if d := math.Abs(float64(report.Visits[0].Distance - degree)); d > closeEnough {
+		t.Errorf("Distance inside U was %f, wanted %f", report.Visits[0].Distance, degree)
+	}
+
+	// Through both prongs, starting and ending outside
+	trajectory = []latlong.LatLonger{
+		latlong.Coordinate{Latitude: 0, Longitude: -0.5},
+		latlong.Coordinate{Latitude: 0, Longitude: 3.5},
+	}
+	report = Evaluate(fences, trajectory, latlong.Distance, nvector.Interpolate)
+	if len(report.Events) != 4 {
+		t.Errorf("Got events %+v, wanted two entries and two exits", report.Events)
+	}
+	for i, e := range report.Events {
+		if e.Entered != (i%2 == 0) || e.Fix != 1 {
+			t.Errorf("Event %d was %+v", i, e)
+		}
+	}
+	if len(report.Visits) != 1 || report.Visits[0].Entries != 2 || len(report.Violations) != 2 {
+		t.Fatalf("Got %+v, wanted two entries into U", report)
+	}
+	if d := math.Abs(float64(report.Visits[0].Distance - 2*degree)); d > closeEnough {
+		t.Errorf("Distance inside U was %f, wanted %f", report.Visits[0].Distance, 2*degree)
+	}
+}
+
+// Fly due east along a parallel through a fence on it. The rhumb line
+// keeps to the parallel and passes through the fence, while the great
+// circle between the same fixes bows north of it.
+func TestRhumbPassThrough(t *testing.T) {
+	fences, err := Parse([]byte(`[{"Name": "Mast", "Kind": "restricted",
+		"Center": {"Latitude": 60, "Longitude": 0}, "Radius": 20000}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trajectory := []latlong.LatLonger{
+		latlong.Coordinate{Latitude: 60, Longitude: -10},
+		latlong.Coordinate{Latitude: 60, Longitude: 10},
+	}
+
+	if report := Evaluate(fences, trajectory, latlong.Distance, nvector.Interpolate); len(report.Visits) != 0 {
+		t.Errorf("Got %+v along the great circle, wanted no visits", report)
+	}
+	report := Evaluate(fences, trajectory, latlong.RhumbDistance, latlong.RhumbInterpolate)
+	if len(report.Visits) != 1 || len(report.Violations) != 1 {
+		t.Fatalf("Got %+v along the rhumb line, wanted a single pass through Mast", report)
+	}
+	if d := math.Abs(float64(report.Visits[0].Distance - latlong.Meters(40000))); d > 100 {
+		t.Errorf("Distance inside Mast was %f, wanted about 40000", report.Visits[0].Distance)
+	}
+}
+
+// Check that malformed fences are rejected.
+func TestParseErrors(t *testing.T) {
+	bad := []string{
+		`[{"Name": "Nowhere"}]`,
+		`[{"Name": "Line", "Polygon": [{"Latitude": 0, "Longitude": 0}, {"Latitude": 1, "Longitude": 1}]}]`,
+		`{"type": "Feature"}`,
+		`{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"name": "Dot"},
+		  "geometry": {"type": "Point", "coordinates": [5, 0]}}]}`,
+		`{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"name": "Far", "radius": 5},
+		  "geometry": {"type": "Point", "coordinates": [5, 95]}}]}`,
+	}
+	for _, source := range bad {
+		if _, err := Parse([]byte(source)); err == nil {
+			t.Errorf("Parsing %s should fail", source)
+		}
+	}
+}
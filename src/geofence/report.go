@@ -0,0 +1,157 @@
+package geofence
+
+import (
+	"latlong"
+	"sort"
+)
+
+// Event is a traveler crossing the boundary of a fence
+type Event struct {
+	Fence   *Fence
+	Fix     int  // Index of the first fix on the new side of the boundary
+	Entered bool // True on entry, false on exit
+}
+
+// Visit totals the part of a trajectory spent inside one fence.
+// Trajectories carry no timestamps, so time inside is measured by the
+// number of fixes recorded there.
+type Visit struct {
+	Fence    *Fence
+	Entries  int
+	Fixes    int
+	Distance latlong.Length
+}
+
+// Report describes how a trajectory relates to a set of fences
+type Report struct {
+	// Boundary crossings, in the order they happened. A trajectory
+	// that starts inside a fence enters it at its first fix, and the
+	// crossings along a leg are reported at the fix that ends the leg.
+	Events []Event
+	// Fences the trajectory entered, in the order they were given
+	Visits []Visit
+	// Entries into restricted fences
+	Violations []Event
+}
+
+// crossing is a boundary crossing found along a leg, before it is
+// reported as an Event
+type crossing struct {
+	fence   int     // Index of the fence crossed
+	at      float64 // Fraction of the leg flown before the crossing
+	entered bool
+}
+
+// byFraction sorts the crossings along a leg into the order they happen
+type byFraction []crossing
+
+func (c byFraction) Len() int           { return len(c) }
+func (c byFraction) Less(i, j int) bool { return c[i].at < c[j].at }
+func (c byFraction) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// Evaluate follows a trajectory through a set of fences. Legs are
+// measured with the distance function and flown along the path found by
+// interpolate, such as nvector.Interpolate for great circles, which
+// must be the path the distance function measures. Each leg is sampled
+// along its length, so it may leave and enter a fence, such as one
+// shaped like a U, several times between fixes.
+func Evaluate(fences []Fence, trajectory []latlong.LatLonger, distance func(a, b latlong.LatLonger) latlong.Length, interpolate func(a, b latlong.LatLonger, f float64) latlong.Coordinate) Report {
+	var report Report
+	visits := make([]Visit, len(fences))
+	wasInside := make([]bool, len(fences))
+
+	for i, point := range trajectory {
+		var crossings []crossing
+		for j := range fences {
+			fence := &fences[j]
+			inside := fence.Contains(point)
+			visits[j].Fence = fence
+
+			if i == 0 {
+				if inside {
+					crossings = append(crossings, crossing{fence: j, entered: true})
+				}
+			} else {
+				prev := trajectory[i-1]
+				leg := distance(prev, point)
+				var entered float64 // Fraction of the leg flown before the last entry
+				for _, c := range legCrossings(fence, interpolate, prev, point, wasInside[j], inside) {
+					if c.entered {
+						entered = c.at
+					} else {
+						visits[j].Distance += leg * latlong.Length(c.at-entered)
+					}
+					c.fence = j
+					crossings = append(crossings, c)
+				}
+				if inside {
+					visits[j].Distance += leg * latlong.Length(1-entered)
+				}
+			}
+
+			if inside {
+				visits[j].Fixes++
+			}
+			wasInside[j] = inside
+		}
+
+		sort.Stable(byFraction(crossings))
+		for _, c := range crossings {
+			event := Event{Fence: &fences[c.fence], Fix: i, Entered: c.entered}
+			report.Events = append(report.Events, event)
+			if c.entered {
+				visits[c.fence].Entries++
+				if event.Fence.Kind == Restricted {
+					report.Violations = append(report.Violations, event)
+				}
+			}
+		}
+	}
+
+	for _, v := range visits {
+		if v.Entries > 0 {
+			report.Visits = append(report.Visits, v)
+		}
+	}
+	return report
+}
+
+// Number of points sampled along a leg, looking for crossings of the
+// boundary of a fence
+const samples = 64
+
+// Fraction of the leg from a to b flown before crossing the boundary of
+// the fence, found by bisection between the fractions lo and hi, which
+// must lie on opposite sides of the boundary
+func boundary(fence *Fence, interpolate func(a, b latlong.LatLonger, f float64) latlong.Coordinate, a, b latlong.LatLonger, lo, hi float64) float64 {
+	loInside := fence.Contains(interpolate(a, b, lo))
+	for i := 0; i < 30; i++ {
+		mid := (lo + hi) / 2
+		if fence.Contains(interpolate(a, b, mid)) == loInside {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// Crossings of the boundary of the fence along the leg from a to b, in
+// order, given whether a and b are inside it. Every change between
+// samples along the leg is a crossing of its own.
+func legCrossings(fence *Fence, interpolate func(a, b latlong.LatLonger, f float64) latlong.Coordinate, a, b latlong.LatLonger, aInside, bInside bool) []crossing {
+	var crossings []crossing
+	wasInside := aInside
+	for i := 1; i <= samples; i++ {
+		inside := bInside
+		if i < samples {
+			inside = fence.Contains(interpolate(a, b, float64(i)/samples))
+		}
+		if inside != wasInside {
+			at := boundary(fence, interpolate, a, b, float64(i-1)/samples, float64(i)/samples)
+			crossings = append(crossings, crossing{at: at, entered: inside})
+			wasInside = inside
+		}
+	}
+	return crossings
+}
@@ -0,0 +1,161 @@
+// Package geohash encodes positions on earth as geohashes, short
+// strings that name cells of a latitude/longitude grid. Points that
+// share a geohash prefix are close together, which makes geohashes
+// handy for grouping points by region.
+//
+// Reference for geohash can be found here: https://en.wikipedia.org/wiki/Geohash
+package geohash
+
+import (
+	"errors"
+	"fmt"
+	"latlong"
+	"strings"
+)
+
+// MaxPrecision is the longest geohash Encode will produce. Twelve
+// characters name a cell a few centimeters across.
+const MaxPrecision = 12
+
+// Characters of a geohash, each carrying 5 bits
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode finds the geohash of point with the given number of
+// characters, which is clamped to [1, MaxPrecision]. Points whose
+// latitude or longitude is out of range or not a number are rejected
+// with a *latlong.RangeError rather than clamped or wrapped.
+func Encode(point latlong.LatLonger, precision int) (string, error) {
+	if precision < 1 {
+		precision = 1
+	} else if precision > MaxPrecision {
+		precision = MaxPrecision
+	}
+
+	lat, lon := point.Lat(), point.Lon()
+	if err := (latlong.Coordinate{Latitude: lat, Longitude: lon}).Validate(); err != nil {
+		return "", err
+	}
+	latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	hash := make([]byte, precision)
+	even := true // Even bits split longitude, odd bits latitude
+	for i := range hash {
+		var c byte
+		for bit := 0; bit < 5; bit++ {
+			c <<= 1
+			if even {
+				c |= split(&lonRange, lon)
+			} else {
+				c |= split(&latRange, lat)
+			}
+			even = !even
+		}
+		hash[i] = base32[c]
+	}
+	return string(hash), nil
+}
+
+// Halve interval, keeping the half that holds v, and return 1 if that
+// is the upper half
+func split(interval *[2]float64, v float64) byte {
+	mid := (interval[0] + interval[1]) / 2
+	if v >= mid {
+		interval[0] = mid
+		return 1
+	}
+	interval[1] = mid
+	return 0
+}
+
+// Cell is the region of the earth named by a geohash
+type Cell struct {
+	Center         latlong.Coordinate
+	LatitudeError  float64 // Half the height of the cell, in degrees
+	LongitudeError float64 // Half the width of the cell, in degrees
+}
+
+// Bounds of the cell
+func (c Cell) Bounds() latlong.BoundingBox {
+	return latlong.BoundingBox{
+		South: c.Center.Latitude - c.LatitudeError,
+		North: c.Center.Latitude + c.LatitudeError,
+		West:  c.Center.Longitude - c.LongitudeError,
+		East:  c.Center.Longitude + c.LongitudeError,
+	}
+}
+
+// Decode finds the cell a geohash names. Upper and lower case are
+// both accepted.
+func Decode(hash string) (Cell, error) {
+	if hash == "" {
+		return Cell{}, errors.New("Empty geohash")
+	}
+
+	latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+	even := true
+	for _, r := range strings.ToLower(hash) {
+		c := strings.IndexRune(base32, r)
+		if c < 0 {
+			return Cell{}, errors.New(fmt.Sprintf("Invalid character '%c' in geohash '%s'", r, hash))
+		}
+		for bit := 4; bit >= 0; bit-- {
+			half := 1 - (c>>uint(bit))&1 // Index of the bound that moves
+			if even {
+				lonRange[half] = (lonRange[0] + lonRange[1]) / 2
+			} else {
+				latRange[half] = (latRange[0] + latRange[1]) / 2
+			}
+			even = !even
+		}
+	}
+
+	return Cell{
+		Center: latlong.Coordinate{
+			Latitude:  (latRange[0] + latRange[1]) / 2,
+			Longitude: (lonRange[0] + lonRange[1]) / 2,
+		},
+		LatitudeError:  (latRange[1] - latRange[0]) / 2,
+		LongitudeError: (lonRange[1] - lonRange[0]) / 2,
+	}, nil
+}
+
+// Directions of the neighbors returned by Neighbors
+const (
+	North = iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+// Neighbors finds the geohashes of the 8 cells of the same size around
+// a geohash, indexed by direction (North, NorthEast, ...). Cells wrap
+// around the antimeridian. There are no cells beyond the poles, so the
+// northern neighbors of a cell on the north pole, and the southern
+// neighbors of a cell on the south pole, are empty strings.
+func Neighbors(hash string) ([8]string, error) {
+	var neighbors [8]string
+	if len(hash) > MaxPrecision {
+		return neighbors, errors.New(fmt.Sprintf("Geohash '%s' is longer than %d characters", hash, MaxPrecision))
+	}
+	cell, err := Decode(hash)
+	if err != nil {
+		return neighbors, err
+	}
+
+	steps := [8][2]float64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	for direction, step := range steps {
+		lat := cell.Center.Latitude + 2*step[0]*cell.LatitudeError
+		if lat > 90 || lat < -90 {
+			continue
+		}
+		lon := cell.Center.Longitude + 2*step[1]*cell.LongitudeError
+		neighbor := latlong.Coordinate{Latitude: lat, Longitude: lon}.Normalize()
+		if neighbors[direction], err = Encode(neighbor, len(hash)); err != nil {
+			return neighbors, err
+		}
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,112 @@
+package geohash
+
+import (
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between floating point values
+)
+
+// Known geohashes, from the Wikipedia article and geohash.org.
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		point latlong.Coordinate
+		hash  string
+	}{
+		{latlong.Coordinate{Latitude: 57.64911, Longitude: 10.40744}, "u4pruydqqvj"},
+		{latlong.Coordinate{Latitude: 42.6, Longitude: -5.6}, "ezs42"},
+		{latlong.Coordinate{Latitude: -25.382708, Longitude: -49.265506}, "6gkzwgjzn820"},
+		{latlong.Coordinate{Latitude: 0, Longitude: 0}, "s0000"},
+		{latlong.Coordinate{Latitude: -90, Longitude: -180}, "00000"},
+		{latlong.Coordinate{Latitude: 90, Longitude: 180}, "zzzzz"},
+	}
+	for _, c := range cases {
+		if got, err := Encode(c.point, len(c.hash)); err != nil || got != c.hash {
+			t.Errorf("Encode(%v, %d) was %s, %v, wanted %s", c.point, len(c.hash), got, err, c.hash)
+		}
+	}
+	if got, err := Encode(cases[0].point, 20); err != nil || len(got) != MaxPrecision {
+		t.Errorf("Encode at precision 20 gave %s, %v", got, err)
+	}
+
+	bad := []latlong.Coordinate{
+		{Latitude: 90.5, Longitude: 0},
+		{Latitude: 0, Longitude: -180.5},
+		{Latitude: math.NaN(), Longitude: 0},
+		{Latitude: 0, Longitude: math.Inf(1)},
+	}
+	for _, point := range bad {
+		if got, err := Encode(point, 5); err == nil {
+			t.Errorf("Encode(%v, 5) was %s, wanted an error", point, got)
+		}
+	}
+}
+
+// Generate 100,000 random lat/long coordinates, encode them at random
+// precisions, decode them, and assert that the cell holds the original
+// point and encodes back to the same geohash.
+func TestRandDecode(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		point := latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+		}
+		hash, err := Encode(point, 1+rand.Intn(MaxPrecision))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cell, err := Decode(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cell.Bounds().Contains(point) {
+			t.Errorf("Cell %+v of %s does not hold %v", cell, hash, point)
+		}
+		if got, err := Encode(cell.Center, len(hash)); err != nil || got != hash {
+			t.Errorf("Center of %s encodes as %s, %v", hash, got, err)
+		}
+	}
+
+	if _, err := Decode("ezs4a"); err == nil {
+		t.Errorf("Decoding a geohash with an 'a' succeeded")
+	}
+	if _, err := Decode(""); err == nil {
+		t.Errorf("Decoding an empty geohash succeeded")
+	}
+	if cell, err := Decode("EZS42"); err != nil || math.Abs(cell.Center.Latitude-42.60498046875) > closeEnough {
+		t.Errorf("Decoding upper case geohash gave %+v, %v", cell, err)
+	}
+}
+
+// Check neighbors, including across the antimeridian and at the poles.
+func TestNeighbors(t *testing.T) {
+	got, err := Neighbors("gbsuv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [8]string{"gbsvj", "gbsvn", "gbsuy", "gbsuw", "gbsut", "gbsus", "gbsuu", "gbsvh"}
+	if got != want {
+		t.Errorf("Neighbors(gbsuv) were %v, wanted %v", got, want)
+	}
+
+	// The cell east of the antimeridian is in the far west
+	e, _ := Encode(latlong.Coordinate{Latitude: 0.01, Longitude: 179.99}, 6)
+	east, _ := Neighbors(e)
+	if w, _ := Encode(latlong.Coordinate{Latitude: 0.01, Longitude: -179.99}, 6); east[East] != w {
+		t.Errorf("East of the antimeridian was %s, wanted %s", east[East], w)
+	}
+
+	polar, _ := Neighbors("zzzz")
+	if polar[North] != "" || polar[NorthEast] != "" || polar[NorthWest] != "" || polar[South] == "" {
+		t.Errorf("Neighbors of a polar cell were %v", polar)
+	}
+
+	if _, err := Neighbors("u4pruydqqvjzz"); err == nil {
+		t.Errorf("Neighbors of an overlong geohash succeeded")
+	}
+}
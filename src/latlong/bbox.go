@@ -0,0 +1,156 @@
+package latlong
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// BoundingBox is the region between two parallels and two meridians.
+// Longitudes run east from West to East, so a box that crosses the
+// antimeridian has West greater than East. A box covering every
+// longitude runs from -180 to 180.
+type BoundingBox struct {
+	South, North float64 // Latitudes in degrees
+	West, East   float64 // Longitudes in degrees
+}
+
+// NewBoundingBox finds the smallest box containing every point. It
+// bounds the points themselves, not the great circles between them.
+//
+// Longitudes wrap around, so the box is found by leaving out the
+// widest gap between the points' longitudes rather than taking their
+// minimum and maximum, which keeps sets that straddle the antimeridian
+// from spanning the whole globe.
+func NewBoundingBox(points []LatLonger) (BoundingBox, error) {
+	if len(points) == 0 {
+		return BoundingBox{}, errors.New("Cannot bound an empty set of points")
+	}
+
+	b := BoundingBox{South: 90, North: -90}
+	var lons []float64
+	for _, p := range points {
+		b.South = math.Min(b.South, p.Lat())
+		b.North = math.Max(b.North, p.Lat())
+		// Every longitude meets at the poles
+		if math.Abs(p.Lat()) < 90 {
+			lons = append(lons, angNormalize(p.Lon()))
+		}
+	}
+	if len(lons) == 0 {
+		b.West, b.East = -180, 180
+		return b, nil
+	}
+
+	sort.Float64s(lons)
+	// Start with the gap that wraps from the last longitude to the first
+	b.West, b.East = lons[0], lons[len(lons)-1]
+	gap := lons[0] + 360 - lons[len(lons)-1]
+	for i := 1; i < len(lons); i++ {
+		if d := lons[i] - lons[i-1]; d > gap {
+			gap = d
+			b.West, b.East = lons[i], lons[i-1]
+		}
+	}
+	return b, nil
+}
+
+// Degrees east from one longitude to another, in [0, 360)
+func eastOf(from, to float64) float64 {
+	d := math.Mod(to-from, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// Width of the box in degrees of longitude
+func (b BoundingBox) width() float64 {
+	if b.West == -180 && b.East == 180 {
+		return 360
+	}
+	return eastOf(b.West, b.East)
+}
+
+// True if the box spans the meridian at lon
+func (b BoundingBox) hasLon(lon float64) bool {
+	return b.width() == 360 || eastOf(b.West, lon) <= b.width()
+}
+
+// True if the box spans every meridian that o spans
+func (b BoundingBox) coversLons(o BoundingBox) bool {
+	if b.width() == 360 {
+		return true
+	}
+	return o.width() < 360 && eastOf(b.West, o.West)+o.width() <= b.width()
+}
+
+// CrossesAntimeridian reports whether the box spans the ±180° meridian
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.West > b.East
+}
+
+// Contains reports whether point lies inside the box or on its edge
+func (b BoundingBox) Contains(point LatLonger) bool {
+	lat := point.Lat()
+	return lat >= b.South && lat <= b.North && (math.Abs(lat) == 90 || b.hasLon(point.Lon()))
+}
+
+// Intersects reports whether the two boxes share any point
+func (b BoundingBox) Intersects(o BoundingBox) bool {
+	if b.South > o.North || o.South > b.North {
+		return false
+	}
+	return b.hasLon(o.West) || o.hasLon(b.West)
+}
+
+// Union is the smallest box containing both boxes
+func (b BoundingBox) Union(o BoundingBox) BoundingBox {
+	u := BoundingBox{South: math.Min(b.South, o.South), North: math.Max(b.North, o.North)}
+
+	// The union starts at one box's west edge and ends at one box's
+	// east edge; take the narrowest such span that covers both
+	best := 360.0
+	for _, west := range []float64{b.West, o.West} {
+		for _, east := range []float64{b.East, o.East} {
+			span := BoundingBox{West: west, East: east}
+			if span.coversLons(b) && span.coversLons(o) && span.width() < best {
+				best = span.width()
+				u.West, u.East = west, east
+			}
+		}
+	}
+	if best == 360 {
+		u.West, u.East = -180, 180
+	}
+	return u
+}
+
+// Expand grows the box by distance in every direction on the
+// spherical earth, so that it contains every point within distance of
+// the original box. Boxes that grow over a pole span every longitude.
+func (b BoundingBox) Expand(distance Length) BoundingBox {
+	r := float64(distance / earthRadius) // Angular distance in radians
+	e := BoundingBox{South: b.South - deg(r), North: b.North + deg(r)}
+	if e.South <= -90 || e.North >= 90 {
+		e.South, e.North = math.Max(e.South, -90), math.Min(e.North, 90)
+		e.West, e.East = -180, 180
+		return e
+	}
+
+	// Meridians are closest together at the latitude farthest from
+	// the equator, so the box must widen the most there
+	maxLat := rad(math.Max(math.Abs(e.South), math.Abs(e.North)))
+	sinLon := math.Sin(r) / math.Cos(maxLat)
+	if sinLon >= 1 {
+		e.West, e.East = -180, 180
+		return e
+	}
+	lon := deg(math.Asin(sinLon))
+	if b.width()+2*lon >= 360 {
+		e.West, e.East = -180, 180
+		return e
+	}
+	e.West, e.East = angNormalize(b.West-lon), angNormalize(b.East+lon)
+	return e
+}
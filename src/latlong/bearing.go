@@ -0,0 +1,47 @@
+package latlong
+
+import (
+	"math"
+)
+
+// Reduce a bearing in degrees to the range [0, 360)
+func wrap360(bearing float64) float64 {
+	b := math.Mod(bearing, 360)
+	if b < 0 {
+		b += 360
+	}
+	return b
+}
+
+// InitialBearing (forward azimuth) at a when flying the great circle
+// from a to b, in degrees clockwise from true north in [0, 360)
+func InitialBearing(a, b LatLonger) float64 {
+	latA, lonA := rad(a.Lat()), rad(a.Lon())
+	latB, lonB := rad(b.Lat()), rad(b.Lon())
+
+	y := math.Sin(lonB-lonA) * math.Cos(latB)
+	x := math.Cos(latA)*math.Sin(latB) - math.Sin(latA)*math.Cos(latB)*math.Cos(lonB-lonA)
+
+	return wrap360(deg(math.Atan2(y, x)))
+}
+
+// FinalBearing on arrival at b when flying the great circle from a to
+// b, in degrees clockwise from true north in [0, 360)
+func FinalBearing(a, b LatLonger) float64 {
+	return wrap360(InitialBearing(b, a) + 180)
+}
+
+// InitialBearing (forward azimuth) at a when flying the geodesic from
+// a to b on the ellipsoid, in degrees clockwise from true north in
+// [0, 360)
+func (e *Ellipsoid) InitialBearing(a, b LatLonger) float64 {
+	_, azi1, _ := e.geod.inverse(a.Lat(), a.Lon(), b.Lat(), b.Lon())
+	return wrap360(azi1)
+}
+
+// FinalBearing on arrival at b when flying the geodesic from a to b on
+// the ellipsoid, in degrees clockwise from true north in [0, 360)
+func (e *Ellipsoid) FinalBearing(a, b LatLonger) float64 {
+	_, _, azi2 := e.geod.inverse(a.Lat(), a.Lon(), b.Lat(), b.Lon())
+	return wrap360(azi2)
+}
@@ -11,6 +11,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 // Convert angle in radians to angle in degrees
@@ -19,10 +21,93 @@ func deg(rad float64) float64 { return rad * 180 / math.Pi }
 // Convert angle in degrees to angle in radians
 func rad(deg float64) float64 { return deg * math.Pi / 180 }
 
-// Coordinate represents a position on earth by latitude and longitude
+// Coordinate represents a position on earth by latitude and longitude,
+// and optionally by altitude
 type Coordinate struct {
 	Latitude  float64
 	Longitude float64
+	Altitude  Length // Height above the reference ellipsoid
+}
+
+// RangeError reports a latitude or longitude that is out of range, or
+// a latitude, longitude or altitude that is not a number
+type RangeError struct {
+	Field string // "Latitude", "Longitude" or "Altitude"
+	Value float64
+}
+
+func (e *RangeError) Error() string {
+	if e.Field == "Altitude" {
+		return fmt.Sprintf("%s %g is not a finite number", e.Field, e.Value)
+	}
+	limit := 90
+	if e.Field == "Longitude" {
+		limit = 180
+	}
+	return fmt.Sprintf("%s %g is out of range [-%d, %d]", e.Field, e.Value, limit, limit)
+}
+
+// NewCoordinate creates a Coordinate, rejecting latitudes outside
+// [-90, 90], longitudes outside [-180, 180] and values that are not
+// finite numbers
+func NewCoordinate(latitude, longitude float64) (Coordinate, error) {
+	c := Coordinate{Latitude: latitude, Longitude: longitude}
+	if err := c.Validate(); err != nil {
+		return Coordinate{}, err
+	}
+	return c, nil
+}
+
+// NewNormalizedCoordinate creates a Coordinate from any finite latitude
+// and longitude, normalizing them as Normalize does. Set the Altitude
+// of the result for a position above the ellipsoid.
+func NewNormalizedCoordinate(latitude, longitude float64) (Coordinate, error) {
+	if math.IsNaN(latitude) || math.IsInf(latitude, 0) {
+		return Coordinate{}, &RangeError{"Latitude", latitude}
+	}
+	if math.IsNaN(longitude) || math.IsInf(longitude, 0) {
+		return Coordinate{}, &RangeError{"Longitude", longitude}
+	}
+	return Coordinate{Latitude: latitude, Longitude: longitude}.Normalize(), nil
+}
+
+// Validate returns a *RangeError if the latitude is outside [-90, 90],
+// the longitude is outside [-180, 180] or any field is not a number
+func (c Coordinate) Validate() error {
+	if !(math.Abs(c.Latitude) <= 90) {
+		return &RangeError{"Latitude", c.Latitude}
+	}
+	if !(math.Abs(c.Longitude) <= 180) {
+		return &RangeError{"Longitude", c.Longitude}
+	}
+	if math.IsNaN(float64(c.Altitude)) || math.IsInf(float64(c.Altitude), 0) {
+		return &RangeError{"Altitude", float64(c.Altitude)}
+	}
+	return nil
+}
+
+// Normalize returns the same position with its latitude in [-90, 90]
+// and its longitude in [-180, 180). Latitudes past a pole fold back
+// over it onto the opposite meridian, so latitude 100 at longitude 0
+// becomes latitude 80 at longitude -180. Values that are not finite
+// are left alone.
+func (c Coordinate) Normalize() Coordinate {
+	lat, lon := math.Remainder(c.Latitude, 360), c.Longitude
+	if lat > 90 {
+		lat, lon = 180-lat, lon+180
+	} else if lat < -90 {
+		lat, lon = -180-lat, lon+180
+	}
+
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	if lon >= 360 {
+		// Adding 360 to a tiny negative remainder rounds up to 360
+		lon = 0
+	}
+	return Coordinate{Latitude: lat, Longitude: lon - 180, Altitude: c.Altitude}
 }
 
 func (c *Coordinate) UnmarshalJSON(b []byte) error {
@@ -31,8 +116,9 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	// Check number of fields in JSON object
-	if len(obj) > 2 {
+	// Check number of fields in JSON object. The Altitude field is optional.
+	_, hasAltitude := obj["Altitude"]
+	if len(obj) > 3 || (len(obj) == 3 && !hasAltitude) {
 		return errors.New(fmt.Sprintf("Too many fields for latlong.Coordinate"))
 	}
 	if len(obj) < 2 {
@@ -55,9 +141,85 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 		return errors.New("Wrong type for field 'Longitude'")
 	}
 
+	// Check Altitude
+	var altitude float64
+	if hasAltitude {
+		var ok bool
+		if altitude, ok = obj["Altitude"].(float64); !ok {
+			return errors.New("Wrong type for field 'Altitude'")
+		}
+	}
+
+	// Check ranges
+	parsed := Coordinate{
+		Latitude:  obj["Latitude"].(float64),
+		Longitude: obj["Longitude"].(float64),
+		Altitude:  Meters(altitude),
+	}
+	if err := parsed.Validate(); err != nil {
+		return err
+	}
+
 	// All clear
-	c.Latitude = obj["Latitude"].(float64)
-	c.Longitude = obj["Longitude"].(float64)
+	*c = parsed
+	return nil
+}
+
+// MarshalJSON encodes the coordinate as an object with the fields
+// UnmarshalJSON requires, with the altitude in meters if it is not
+// zero. Coordinates that Validate rejects are refused.
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Latitude, Longitude float64
+		Altitude            float64 `json:",omitempty"`
+	}{c.Latitude, c.Longitude, c.Altitude.Meters()})
+}
+
+// MarshalText encodes the coordinate as signed decimal degrees, e.g.
+// "40.446195 -79.982222", followed by the altitude in meters if it is
+// not zero. Coordinates that Validate rejects are refused.
+func (c Coordinate) MarshalText() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	b := strconv.AppendFloat(nil, c.Latitude, 'f', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, c.Longitude, 'f', -1, 64)
+	if c.Altitude != 0 {
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, c.Altitude.Meters(), 'f', -1, 64)
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes any coordinate string that ParseCoordinate
+// accepts, optionally followed by an altitude in meters as written by
+// MarshalText
+func (c *Coordinate) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	parsed, err := ParseCoordinate(s)
+	if err != nil {
+		// Try again with the last field as the altitude
+		i := strings.LastIndexAny(s, " \t")
+		if i < 0 {
+			return err
+		}
+		altitude, e := strconv.ParseFloat(s[i+1:], 64)
+		if e != nil {
+			return err
+		}
+		if parsed, e = ParseCoordinate(s[:i]); e != nil {
+			return err
+		}
+		parsed.Altitude = Meters(altitude)
+		if e := parsed.Validate(); e != nil {
+			return e
+		}
+	}
+	*c = parsed
 	return nil
 }
 
@@ -68,3 +230,7 @@ func (c Coordinate) Lat() float64 {
 func (c Coordinate) Lon() float64 {
 	return c.Longitude
 }
+
+func (c Coordinate) Alt() Length {
+	return c.Altitude
+}
@@ -0,0 +1,282 @@
+package latlong
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AngleFormat selects how Coordinate.Format writes each angle
+type AngleFormat int
+
+const (
+	// Decimal degrees, e.g. 40.446195°
+	Decimal AngleFormat = iota
+	// Degrees and decimal minutes, e.g. 40°26.772'
+	DegreesMinutes
+	// Degrees, minutes and decimal seconds, e.g. 40°26'46.3"
+	DegreesMinutesSeconds
+)
+
+// HemisphereStyle selects how Coordinate.Format tells north from south
+// and east from west
+type HemisphereStyle int
+
+const (
+	// Hemisphere letter after each angle, e.g. 40°26'46"N 79°58'56"W
+	HemisphereSuffix HemisphereStyle = iota
+	// Hemisphere letter before each angle, e.g. N40°26'46" W79°58'56"
+	HemispherePrefix
+	// Negative angles to the south and west, e.g. 40°26'46" -79°58'56"
+	HemisphereSign
+)
+
+// Style configures Coordinate.Format
+type Style struct {
+	Format     AngleFormat
+	Precision  int // Digits after the decimal point of the last field
+	Hemisphere HemisphereStyle
+}
+
+// Format writes the coordinate as latitude then longitude, in degrees,
+// minutes and seconds as selected by style
+func (c Coordinate) Format(style Style) string {
+	return formatAngle(c.Latitude, "N", "S", style) + " " + formatAngle(c.Longitude, "E", "W", style)
+}
+
+func formatAngle(angle float64, positive, negative string, style Style) string {
+	precision := style.Precision
+	if precision < 0 {
+		precision = 0
+	}
+
+	// Round once, in units of the last field, so that rounding carries
+	// into minutes and degrees, e.g. 59.96" becomes 1' 0.0"
+	perDegree := []float64{1, 60, 3600}[style.Format]
+	scale := math.Pow(10, float64(precision))
+	units := math.Floor(math.Abs(angle)*perDegree*scale + 0.5)
+
+	var buf bytes.Buffer
+	hemisphere := positive
+	if angle < 0 && units > 0 {
+		hemisphere = negative
+	}
+	switch style.Hemisphere {
+	case HemispherePrefix:
+		buf.WriteString(hemisphere)
+	case HemisphereSign:
+		if hemisphere == negative {
+			buf.WriteString("-")
+		}
+	}
+
+	width := precision + 3 // Two digits, a point and the decimals
+	if precision == 0 {
+		width = 2
+	}
+	unitsPerDegree, unitsPerMinute := perDegree*scale, perDegree*scale/60
+	switch style.Format {
+	case Decimal:
+		fmt.Fprintf(&buf, "%.*f°", precision, units/scale)
+	case DegreesMinutes:
+		fmt.Fprintf(&buf, "%.0f°%0*.*f'", math.Floor(units/unitsPerDegree), width, precision,
+			math.Mod(units, unitsPerDegree)/scale)
+	case DegreesMinutesSeconds:
+		fmt.Fprintf(&buf, "%.0f°%02.0f'%0*.*f\"", math.Floor(units/unitsPerDegree),
+			math.Floor(math.Mod(units, unitsPerDegree)/unitsPerMinute), width, precision,
+			math.Mod(units, unitsPerMinute)/scale)
+	}
+
+	if style.Hemisphere == HemisphereSuffix {
+		buf.WriteString(hemisphere)
+	}
+	return buf.String()
+}
+
+// A token of a coordinate string: a number, a hemisphere letter or a
+// comma
+type dmsToken struct {
+	number float64
+	text   string // Source of the number, or the letter or comma
+	letter bool
+	comma  bool
+}
+
+// Marks that may separate degrees, minutes and seconds
+const dmsSeparators = "°º'′\"″:"
+
+func tokenizeDMS(s string) ([]dmsToken, error) {
+	var tokens []dmsToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r) || strings.ContainsRune(dmsSeparators, r):
+			i++
+		case r == ',':
+			tokens = append(tokens, dmsToken{text: ",", comma: true})
+			i++
+		case strings.ContainsRune("NSEWnsew", r):
+			tokens = append(tokens, dmsToken{text: strings.ToUpper(string(r)), letter: true})
+			i++
+		case r == '-' || r == '+' || r == '.' || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(runes) && (runes[j] == '.' || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			text := string(runes[i:j])
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Bad number '%s' in coordinate", text))
+			}
+			tokens = append(tokens, dmsToken{number: n, text: text})
+			i = j
+		default:
+			return nil, errors.New(fmt.Sprintf("Unexpected '%c' in coordinate", r))
+		}
+	}
+	return tokens, nil
+}
+
+// An angle of a coordinate string: up to three numbers and an optional
+// hemisphere letter
+type dmsAngle struct {
+	numbers    []dmsToken
+	hemisphere string
+}
+
+// Split the tokens into latitude and longitude. Hemisphere letters and
+// commas mark where one angle ends and the next begins; without them
+// the numbers are shared evenly.
+func splitDMS(tokens []dmsToken) ([2]dmsAngle, error) {
+	var angles [2]dmsAngle
+	var letters, commas int
+	for _, t := range tokens {
+		if t.letter {
+			letters++
+		} else if t.comma {
+			commas++
+		}
+	}
+
+	bad := errors.New("Coordinate needs a latitude and a longitude")
+	switch {
+	case letters == 2:
+		// Letters either all lead or all trail their numbers
+		prefix := false
+		for _, t := range tokens {
+			if !t.comma {
+				prefix = t.letter
+				break
+			}
+		}
+		n, seen := 0, 0
+		for _, t := range tokens {
+			switch {
+			case t.comma:
+				continue
+			case t.letter && prefix:
+				n = seen
+				seen++
+				angles[n].hemisphere = t.text
+			case t.letter:
+				angles[n].hemisphere = t.text
+				n++
+			default:
+				if n > 1 {
+					return angles, bad
+				}
+				angles[n].numbers = append(angles[n].numbers, t)
+			}
+		}
+	case letters == 0 && commas == 1:
+		n := 0
+		for _, t := range tokens {
+			if t.comma {
+				n++
+			} else {
+				angles[n].numbers = append(angles[n].numbers, t)
+			}
+		}
+	case letters == 0 && commas == 0 && len(tokens)%2 == 0:
+		angles[0].numbers = tokens[:len(tokens)/2]
+		angles[1].numbers = tokens[len(tokens)/2:]
+	default:
+		return angles, bad
+	}
+	return angles, nil
+}
+
+// Value of an angle in degrees
+func (a dmsAngle) degrees() (float64, error) {
+	if len(a.numbers) < 1 || len(a.numbers) > 3 {
+		return 0, errors.New("Angle needs degrees, and optionally minutes and seconds")
+	}
+
+	var value float64
+	negative := false
+	for i, n := range a.numbers {
+		signed := strings.HasPrefix(n.text, "-") || strings.HasPrefix(n.text, "+")
+		if i > 0 && (signed || n.number >= 60) {
+			return 0, errors.New(fmt.Sprintf("Bad minutes or seconds '%s'", n.text))
+		}
+		if i < len(a.numbers)-1 && n.number != math.Trunc(n.number) {
+			return 0, errors.New(fmt.Sprintf("Only the last field of an angle may have decimals, not '%s'", n.text))
+		}
+		if i == 0 {
+			negative = strings.HasPrefix(n.text, "-")
+			if negative && a.hemisphere != "" {
+				return 0, errors.New(fmt.Sprintf("Angle '%s' has both a sign and a hemisphere", n.text))
+			}
+		}
+		value += math.Abs(n.number) / math.Pow(60, float64(i))
+	}
+	if negative || a.hemisphere == "S" || a.hemisphere == "W" {
+		value = -value
+	}
+	return value, nil
+}
+
+// ParseCoordinate reads a latitude and longitude written in decimal
+// degrees, degrees and decimal minutes or degrees, minutes and
+// seconds, such as
+//
+//     40°26'46.3"N 79°58'56"W
+//     N40 26.772 W79 58.933
+//     40.446195, -79.982222
+//
+// Latitude comes first unless hemisphere letters say otherwise.
+// Without hemisphere letters, south and west are negative.
+func ParseCoordinate(s string) (Coordinate, error) {
+	tokens, err := tokenizeDMS(s)
+	if err != nil {
+		return Coordinate{}, err
+	}
+	angles, err := splitDMS(tokens)
+	if err != nil {
+		return Coordinate{}, err
+	}
+
+	if angles[0].hemisphere == "E" || angles[0].hemisphere == "W" {
+		angles[0], angles[1] = angles[1], angles[0]
+	}
+	if strings.ContainsAny(angles[0].hemisphere, "EW") || strings.ContainsAny(angles[1].hemisphere, "NS") {
+		return Coordinate{}, errors.New("Coordinate needs one north/south and one east/west hemisphere")
+	}
+
+	var c Coordinate
+	if c.Latitude, err = angles[0].degrees(); err != nil {
+		return Coordinate{}, err
+	}
+	if c.Longitude, err = angles[1].degrees(); err != nil {
+		return Coordinate{}, err
+	}
+	if err := c.Validate(); err != nil {
+		return Coordinate{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,133 @@
+package latlong
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ellipsoid is a reference ellipsoid of revolution, the figure of the
+// earth that a geodetic datum is built on
+//
+// Reference for reference ellipsoids can be found here:
+//     - https://en.wikipedia.org/wiki/Earth_ellipsoid
+type Ellipsoid struct {
+	name string
+	a    float64 // Equatorial radius in meters
+	f    float64 // Flattening
+
+	geod *geodesic
+}
+
+// Common reference ellipsoids
+var (
+	// World Geodetic System 1984, used by GPS
+	WGS84 = mustEllipsoid("WGS84", 6378137, 298.257223563)
+	// Geodetic Reference System 1980, used by NAD83 and ETRS89
+	GRS80 = mustEllipsoid("GRS80", 6378137, 298.257222101)
+	// Clarke 1866, used by NAD27
+	Clarke1866 = mustEllipsoid("Clarke1866", 6378206.4, 294.978698214)
+	// International 1924 (Hayford), used by ED50
+	International1924 = mustEllipsoid("International1924", 6378388, 297)
+	// Airy 1830, used by OSGB36
+	Airy1830 = mustEllipsoid("Airy1830", 6377563.396, 299.3249646)
+)
+
+// Ellipsoids known to LookupEllipsoid, by lowercase name
+var ellipsoids = map[string]*Ellipsoid{}
+
+func init() {
+	for _, e := range []*Ellipsoid{WGS84, GRS80, Clarke1866, International1924, Airy1830} {
+		ellipsoids[strings.ToLower(e.name)] = e
+	}
+}
+
+// NewEllipsoid creates a user-defined Ellipsoid from its equatorial
+// radius a (in meters) and its inverse flattening invF. An inverse
+// flattening of 0 describes a sphere of radius a.
+func NewEllipsoid(name string, a, invF float64) (*Ellipsoid, error) {
+	if !(a > 0) {
+		return nil, errors.New(fmt.Sprintf("Equatorial radius of ellipsoid %s must be positive", name))
+	}
+	if invF != 0 && !(invF > 1) {
+		return nil, errors.New(fmt.Sprintf("Inverse flattening of ellipsoid %s must be 0 or greater than 1", name))
+	}
+
+	f := 0.0
+	if invF != 0 {
+		f = 1 / invF
+	}
+	return &Ellipsoid{name: name, a: a, f: f, geod: newGeodesic(a, f)}, nil
+}
+
+func mustEllipsoid(name string, a, invF float64) *Ellipsoid {
+	e, err := NewEllipsoid(name, a, invF)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// LookupEllipsoid finds one of the common reference ellipsoids by
+// name, ignoring case
+func LookupEllipsoid(name string) (*Ellipsoid, error) {
+	if e, ok := ellipsoids[strings.ToLower(name)]; ok {
+		return e, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown ellipsoid '%s'", name))
+}
+
+func (e *Ellipsoid) String() string {
+	return e.name
+}
+
+// Name of the ellipsoid
+func (e *Ellipsoid) Name() string {
+	return e.name
+}
+
+// SemiMajorAxis is the equatorial radius of the ellipsoid in meters
+func (e *Ellipsoid) SemiMajorAxis() float64 {
+	return e.a
+}
+
+// SemiMinorAxis is the polar radius of the ellipsoid in meters
+func (e *Ellipsoid) SemiMinorAxis() float64 {
+	return e.geod.b
+}
+
+// Flattening of the ellipsoid, (a - b) / a
+func (e *Ellipsoid) Flattening() float64 {
+	return e.f
+}
+
+// EccentricitySquared of the ellipsoid, (a² - b²) / a²
+func (e *Ellipsoid) EccentricitySquared() float64 {
+	return e.geod.e2
+}
+
+// Distance between two LatLongers: a and b, measured along the
+// shortest path (geodesic) on the ellipsoid
+func (e *Ellipsoid) Distance(a, b LatLonger) Length {
+	s12, _, _ := e.geod.inverse(a.Lat(), a.Lon(), b.Lat(), b.Lon())
+	return Meters(s12)
+}
+
+// Destination reached by flying the geodesic on the ellipsoid from
+// start, leaving on the given bearing (degrees clockwise from true
+// north) for distance
+func (e *Ellipsoid) Destination(start LatLonger, bearing float64, distance Length) Coordinate {
+	lat2, lon2, _ := e.geod.direct(start.Lat(), start.Lon(), bearing, distance.Meters())
+	return Coordinate{Latitude: lat2, Longitude: lon2}
+}
+
+// Interpolate finds the point a fraction f of the way along the
+// geodesic on the ellipsoid from a to b, where f = 0 gives a and f = 1
+// gives b
+func (e *Ellipsoid) Interpolate(a, b LatLonger, f float64) Coordinate {
+	s12, azi1, _ := e.geod.inverse(a.Lat(), a.Lon(), b.Lat(), b.Lon())
+	if s12 == 0 {
+		return Coordinate{Latitude: a.Lat(), Longitude: a.Lon()}
+	}
+	return e.Destination(a, azi1, Meters(s12*f))
+}
@@ -0,0 +1,978 @@
+package latlong
+
+// This file is a Go port of the geodesic routines from Charles
+// F. F. Karney's GeographicLib, which is licensed under the MIT/X11
+// License. The algorithms converge for all pairs of points, including
+// nearly antipodal ones where Vincenty's method fails.
+//
+// References:
+//     - C. F. F. Karney, Algorithms for geodesics,
+//       J. Geodesy 87, 43-55 (2013), https://doi.org/10.1007/s00190-012-0578-z
+//     - https://geographiclib.sourceforge.io/
+
+import (
+	"math"
+)
+
+const (
+	geodesicOrder = 6
+	nA1           = geodesicOrder
+	nC1           = geodesicOrder
+	nC1p          = geodesicOrder
+	nA2           = geodesicOrder
+	nC2           = geodesicOrder
+	nA3           = geodesicOrder
+	nA3x          = nA3
+	nC3           = geodesicOrder
+	nC3x          = (nC3 * (nC3 - 1)) / 2
+	nC4           = geodesicOrder
+	nC4x          = (nC4 * (nC4 + 1)) / 2
+
+	maxit1 = 20
+	maxit2 = maxit1 + 53 + 10 // 53 is the number of bits in a float64 mantissa
+
+	tiny = 1.4916681462400413e-154 // sqrt of the smallest normal float64
+	tol0 = 2.220446049250313e-16   // machine epsilon
+	tol1 = 200 * tol0
+)
+
+var (
+	tol2    = math.Sqrt(tol0)
+	tolb    = tol0 * tol2
+	xthresh = 1000 * tol2
+)
+
+// A geodesic holds the series coefficients for solving geodesic
+// problems on an ellipsoid of revolution with equatorial radius a
+// (meters) and flattening f
+type geodesic struct {
+	a, f, f1, e2, ep2, n, b, c2, etol2 float64
+
+	a3x [nA3x]float64
+	c3x [nC3x]float64
+	c4x [nC4x]float64
+}
+
+func newGeodesic(a, f float64) *geodesic {
+	g := &geodesic{a: a, f: f}
+	g.f1 = 1 - f
+	g.e2 = f * (2 - f)
+	g.ep2 = g.e2 / sq(g.f1)
+	g.n = f / (2 - f)
+	g.b = a * g.f1
+
+	// Authalic radius squared
+	var t float64
+	switch {
+	case g.e2 == 0:
+		t = 1
+	case g.e2 > 0:
+		t = math.Atanh(math.Sqrt(g.e2)) / math.Sqrt(g.e2)
+	default:
+		t = math.Atan(math.Sqrt(-g.e2)) / math.Sqrt(-g.e2)
+	}
+	g.c2 = (sq(a) + sq(g.b)*t) / 2
+
+	g.etol2 = 0.1 * tol2 / math.Sqrt(math.Max(0.001, math.Abs(f))*math.Min(1, 1-f/2)/2)
+
+	g.a3coeff()
+	g.c3coeff()
+	g.c4coeff()
+	return g
+}
+
+func sq(x float64) float64 { return x * x }
+
+// Error free transformation of a sum: s + t == u + v exactly
+func sumx(u, v float64) (s, t float64) {
+	s = u + v
+	up := s - v
+	vpp := s - up
+	up -= u
+	vpp -= v
+	t = -(up + vpp)
+	return
+}
+
+// Evaluate the polynomial of degree n with coefficients p[s:s+n+1]
+// (highest order first) at x
+func polyval(n int, p []float64, s int, x float64) float64 {
+	y := 0.0
+	if n >= 0 {
+		y = p[s]
+	}
+	for ; n > 0; n-- {
+		s++
+		y = y*x + p[s]
+	}
+	return y
+}
+
+// Reduce an angle in degrees to the range (-180, 180]
+func angNormalize(x float64) float64 {
+	y := math.Remainder(x, 360)
+	if y == -180 {
+		return 180
+	}
+	return y
+}
+
+// Replace latitudes outside [-90, 90] with NaN
+func latFix(x float64) float64 {
+	if math.Abs(x) > 90 {
+		return math.NaN()
+	}
+	return x
+}
+
+// Exact difference y - x of two angles in degrees, reduced to
+// (-180, 180], along with its rounding error e
+func angDiff(x, y float64) (d, e float64) {
+	d, e = sumx(angNormalize(-x), angNormalize(y))
+	d = angNormalize(d)
+	if d == 180 && e > 0 {
+		d = -180
+	}
+	return sumx(d, e)
+}
+
+// Round tiny angles so that values very close to zero become zero
+func angRound(x float64) float64 {
+	const z = 1.0 / 16
+	y := math.Abs(x)
+	if y < z {
+		y = z - (z - y)
+	}
+	return math.Copysign(y, x)
+}
+
+// Sine and cosine of an angle in degrees, exact for multiples of 90
+func sincosd(x float64) (s, c float64) {
+	r := math.Mod(x, 360)
+	q := 0
+	if !math.IsNaN(r) {
+		q = int(math.Floor(r/90 + 0.5))
+	}
+	r = rad(r - 90*float64(q))
+	s, c = math.Sin(r), math.Cos(r)
+	switch ((q % 4) + 4) % 4 {
+	case 1:
+		s, c = c, -s
+	case 2:
+		s, c = -s, -c
+	case 3:
+		s, c = -c, s
+	}
+	c += 0 // turn -0 into +0
+	if x == 0 {
+		s = x
+	}
+	return
+}
+
+// Two argument arctangent in degrees, exact for multiples of 45
+func atan2d(y, x float64) float64 {
+	q := 0
+	if math.Abs(y) > math.Abs(x) {
+		q = 2
+		x, y = y, x
+	}
+	if x < 0 {
+		q++
+		x = -x
+	}
+	ang := deg(math.Atan2(y, x))
+	switch q {
+	case 1:
+		ang = math.Copysign(180, y) - ang
+	case 2:
+		ang = 90 - ang
+	case 3:
+		ang = -90 + ang
+	}
+	return ang
+}
+
+func norm2(x, y float64) (float64, float64) {
+	r := math.Hypot(x, y)
+	return x / r, y / r
+}
+
+// Evaluate sum(c[i] * sin(2*i*x), i, 1, n) if sinp, otherwise
+// sum(c[i] * cos((2*i+1)*x), i, 0, n-1) using Clenshaw summation
+func sinCosSeries(sinp bool, sinx, cosx float64, c []float64) float64 {
+	k := len(c)
+	n := k
+	if sinp {
+		n--
+	}
+	ar := 2 * (cosx - sinx) * (cosx + sinx)
+	y0, y1 := 0.0, 0.0
+	if n&1 != 0 {
+		k--
+		y0 = c[k]
+	}
+	for n /= 2; n > 0; n-- {
+		k--
+		y1 = ar*y0 - y1 + c[k]
+		k--
+		y0 = ar*y1 - y0 + c[k]
+	}
+	if sinp {
+		return 2 * sinx * cosx * y0
+	}
+	return cosx * (y0 - y1)
+}
+
+// Solve the astroid equation for k, used for the starting guess of
+// nearly antipodal points
+func astroid(x, y float64) float64 {
+	p, q := sq(x), sq(y)
+	r := (p + q - 1) / 6
+	if q == 0 && r <= 0 {
+		return 0
+	}
+	S := p * q / 4
+	r2 := sq(r)
+	r3 := r * r2
+	disc := S * (S + 2*r3)
+	u := r
+	if disc >= 0 {
+		T3 := S + r3
+		if T3 < 0 {
+			T3 -= math.Sqrt(disc)
+		} else {
+			T3 += math.Sqrt(disc)
+		}
+		T := math.Cbrt(T3)
+		if T != 0 {
+			u += T + r2/T
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(S + r3))
+		u += 2 * r * math.Cos(ang/3)
+	}
+	v := math.Sqrt(sq(u) + q)
+	var uv float64
+	if u < 0 {
+		uv = q / (v - u)
+	} else {
+		uv = u + v
+	}
+	w := (uv - q) / (2 * v)
+	return uv / (math.Sqrt(uv+sq(w)) + w)
+}
+
+func a1m1f(eps float64) float64 {
+	coeff := []float64{1, 4, 64, 0, 256}
+	m := nA1 / 2
+	t := polyval(m, coeff, 0, sq(eps)) / coeff[m+1]
+	return (t + eps) / (1 - eps)
+}
+
+func c1f(eps float64, c []float64) {
+	coeff := []float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+	eps2 := sq(eps)
+	d := eps
+	o := 0
+	for l := 1; l <= nC1; l++ {
+		m := (nC1 - l) / 2
+		c[l] = d * polyval(m, coeff, o, eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func c1pf(eps float64, c []float64) {
+	coeff := []float64{
+		205, -432, 768, 1536,
+		4005, -4736, 3840, 12288,
+		-225, 116, 384,
+		-7173, 2695, 7680,
+		3467, 7680,
+		38081, 61440,
+	}
+	eps2 := sq(eps)
+	d := eps
+	o := 0
+	for l := 1; l <= nC1p; l++ {
+		m := (nC1p - l) / 2
+		c[l] = d * polyval(m, coeff, o, eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func a2m1f(eps float64) float64 {
+	coeff := []float64{-11, -28, -192, 0, 256}
+	m := nA2 / 2
+	t := polyval(m, coeff, 0, sq(eps)) / coeff[m+1]
+	return (t - eps) / (1 + eps)
+}
+
+func c2f(eps float64, c []float64) {
+	coeff := []float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+	eps2 := sq(eps)
+	d := eps
+	o := 0
+	for l := 1; l <= nC2; l++ {
+		m := (nC2 - l) / 2
+		c[l] = d * polyval(m, coeff, o, eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func (g *geodesic) a3coeff() {
+	coeff := []float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+	o, k := 0, 0
+	for j := nA3 - 1; j >= 0; j-- {
+		m := nA3 - j - 1
+		if j < m {
+			m = j
+		}
+		g.a3x[k] = polyval(m, coeff, o, g.n) / coeff[o+m+1]
+		k++
+		o += m + 2
+	}
+}
+
+func (g *geodesic) c3coeff() {
+	coeff := []float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+	o, k := 0, 0
+	for l := 1; l < nC3; l++ {
+		for j := nC3 - 1; j >= l; j-- {
+			m := nC3 - j - 1
+			if j < m {
+				m = j
+			}
+			g.c3x[k] = polyval(m, coeff, o, g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+func (g *geodesic) c4coeff() {
+	coeff := []float64{
+		97, 15015,
+		1088, 156, 45045,
+		-224, -4784, 1573, 45045,
+		-10656, 14144, -4576, -858, 45045,
+		64, 624, -4576, 6864, -3003, 15015,
+		100, 208, 572, 3432, -12012, 30030, 45045,
+		1, 9009,
+		-2944, 468, 135135,
+		5792, 1040, -1287, 135135,
+		5952, -11648, 9152, -2574, 135135,
+		-64, -624, 4576, -6864, 3003, 135135,
+		8, 10725,
+		1856, -936, 225225,
+		-8448, 4992, -1144, 225225,
+		-1440, 4160, -4576, 1716, 225225,
+		-136, 63063,
+		1024, -208, 105105,
+		3584, -3328, 1144, 315315,
+		-128, 135135,
+		-2560, 832, 405405,
+		128, 99099,
+	}
+	o, k := 0, 0
+	for l := 0; l < nC4; l++ {
+		for j := nC4 - 1; j >= l; j-- {
+			m := nC4 - j - 1
+			g.c4x[k] = polyval(m, coeff, o, g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+func (g *geodesic) a3f(eps float64) float64 {
+	return polyval(nA3-1, g.a3x[:], 0, eps)
+}
+
+func (g *geodesic) c3f(eps float64, c []float64) {
+	mult := 1.0
+	o := 0
+	for l := 1; l < nC3; l++ {
+		m := nC3 - l - 1
+		mult *= eps
+		c[l] = mult * polyval(m, g.c3x[:], o, eps)
+		o += m + 1
+	}
+}
+
+func (g *geodesic) c4f(eps float64, c []float64) {
+	mult := 1.0
+	o := 0
+	for l := 0; l < nC4; l++ {
+		m := nC4 - l - 1
+		c[l] = mult * polyval(m, g.c4x[:], o, eps)
+		o += m + 1
+		mult *= eps
+	}
+}
+
+// Distance, reduced length and geodesic scales along a geodesic on
+// the auxiliary sphere, in units of the semi-minor axis
+func (g *geodesic) lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, cbet1, cbet2 float64,
+	C1a, C2a []float64) (s12b, m12b, m0, M12, M21 float64) {
+
+	A1 := a1m1f(eps)
+	c1f(eps, C1a)
+	A2 := a2m1f(eps)
+	c2f(eps, C2a)
+	m0x := A1 - A2
+	A1++
+	A2++
+
+	B1 := sinCosSeries(true, ssig2, csig2, C1a) - sinCosSeries(true, ssig1, csig1, C1a)
+	s12b = A1 * (sig12 + B1)
+	B2 := sinCosSeries(true, ssig2, csig2, C2a) - sinCosSeries(true, ssig1, csig1, C2a)
+	J12 := m0x*sig12 + (A1*B1 - A2*B2)
+
+	m0 = m0x
+	m12b = dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*J12
+
+	csig12 := csig1*csig2 + ssig1*ssig2
+	t := g.ep2 * (cbet1 - cbet2) * (cbet1 + cbet2) / (dn1 + dn2)
+	M12 = csig12 + (t*ssig2-csig2*J12)*ssig1/dn1
+	M21 = csig12 - (t*ssig1-csig1*J12)*ssig2/dn2
+	return
+}
+
+// Starting guess for the azimuth at point 1 of the inverse problem.
+// Returns sig12 >= 0 if the solution was found directly.
+func (g *geodesic) inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12 float64,
+	C1a, C2a []float64) (sig12, salp1, calp1, salp2, calp2, dnm float64) {
+
+	sig12 = -1
+	salp2, calp2, dnm = math.NaN(), math.NaN(), math.NaN()
+
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+
+	shortline := cbet12 >= 0 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+	var somg12, comg12 float64
+	if shortline {
+		sbetm2 := sq(sbet1 + sbet2)
+		sbetm2 /= sbetm2 + sq(cbet1+cbet2)
+		dnm = math.Sqrt(1 + g.ep2*sbetm2)
+		omg12 := lam12 / (g.f1 * dnm)
+		somg12, comg12 = math.Sin(omg12), math.Cos(omg12)
+	} else {
+		somg12, comg12 = slam12, clam12
+	}
+
+	salp1 = cbet2 * somg12
+	if comg12 >= 0 {
+		calp1 = sbet12 + cbet2*sbet1*sq(somg12)/(1+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*sq(somg12)/(1-comg12)
+	}
+
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	if shortline && ssig12 < g.etol2 {
+		// Really short lines
+		salp2 = cbet1 * somg12
+		if comg12 >= 0 {
+			calp2 = sbet12 - cbet1*sbet2*(sq(somg12)/(1+comg12))
+		} else {
+			calp2 = sbet12 - cbet1*sbet2*(1-comg12)
+		}
+		salp2, calp2 = norm2(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+	} else if math.Abs(g.n) >= 0.1 || csig12 >= 0 || ssig12 >= 6*math.Abs(g.n)*math.Pi*sq(cbet1) {
+		// Nothing to do, the zeroth order spherical approximation is good enough
+	} else {
+		// Nearly antipodal points: scale to the astroid problem
+		var x, y, lamscale, betscale float64
+		lam12x := math.Atan2(-slam12, -clam12)
+		if g.f >= 0 {
+			k2 := sq(sbet1) * g.ep2
+			eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+			lamscale = g.f * cbet1 * g.a3f(eps) * math.Pi
+			betscale = lamscale * cbet1
+			x = lam12x / lamscale
+			y = sbet12a / betscale
+		} else {
+			cbet12a := cbet2*cbet1 - sbet2*sbet1
+			bet12a := math.Atan2(sbet12a, cbet12a)
+			_, m12b, m0, _, _ := g.lengths(g.n, math.Pi+bet12a, sbet1, -cbet1, dn1, sbet2, cbet2, dn2,
+				cbet1, cbet2, C1a, C2a)
+			x = -1 + m12b/(cbet1*cbet2*m0*math.Pi)
+			if x < -0.01 {
+				betscale = sbet12a / x
+			} else {
+				betscale = -g.f * sq(cbet1) * math.Pi
+			}
+			lamscale = betscale / cbet1
+			y = lam12x / lamscale
+		}
+
+		if y > -tol1 && x > -1-xthresh {
+			if g.f >= 0 {
+				salp1 = math.Min(1, -x)
+				calp1 = -math.Sqrt(1 - sq(salp1))
+			} else {
+				if x > -tol1 {
+					calp1 = math.Max(0, x)
+				} else {
+					calp1 = math.Max(-1, x)
+				}
+				salp1 = math.Sqrt(1 - sq(calp1))
+			}
+		} else {
+			k := astroid(x, y)
+			var omg12a float64
+			if g.f >= 0 {
+				omg12a = lamscale * (-x * k / (1 + k))
+			} else {
+				omg12a = lamscale * (-y * (1 + k) / k)
+			}
+			somg12, comg12 = math.Sin(omg12a), -math.Cos(omg12a)
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*sq(somg12)/(1-comg12)
+		}
+	}
+
+	if !(salp1 <= 0) {
+		salp1, calp1 = norm2(salp1, calp1)
+	} else {
+		salp1, calp1 = 1, 0
+	}
+	return
+}
+
+// Longitude difference lam12 for a geodesic leaving point 1 with
+// azimuth alp1, and its derivative with respect to alp1 if diffp
+func (g *geodesic) lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64,
+	diffp bool, C1a, C2a, C3a []float64) (lam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps, domg12, dlam12 float64) {
+
+	if sbet1 == 0 && calp1 == 0 {
+		// Break degeneracy of equatorial line
+		calp1 = -tiny
+	}
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	ssig1 = sbet1
+	somg1 := salp0 * sbet1
+	csig1 = calp1 * cbet1
+	comg1 := csig1
+	ssig1, csig1 = norm2(ssig1, csig1)
+
+	if cbet2 != cbet1 {
+		salp2 = salp0 / cbet2
+	} else {
+		salp2 = salp1
+	}
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		var t float64
+		if cbet1 < -sbet1 {
+			t = (cbet2 - cbet1) * (cbet1 + cbet2)
+		} else {
+			t = (sbet1 - sbet2) * (sbet1 + sbet2)
+		}
+		calp2 = math.Sqrt(sq(calp1*cbet1)+t) / cbet2
+	} else {
+		calp2 = math.Abs(calp1)
+	}
+
+	ssig2 = sbet2
+	somg2 := salp0 * sbet2
+	csig2 = calp2 * cbet2
+	comg2 := csig2
+	ssig2, csig2 = norm2(ssig2, csig2)
+
+	sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+	somg12 := math.Max(0, comg1*somg2-somg1*comg2)
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	k2 := sq(calp0) * g.ep2
+	eps = k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	g.c3f(eps, C3a)
+	B312 := sinCosSeries(true, ssig2, csig2, C3a) - sinCosSeries(true, ssig1, csig1, C3a)
+	domg12 = -g.f * g.a3f(eps) * salp0 * (sig12 + B312)
+	lam12 = eta + domg12
+
+	if diffp {
+		if calp2 == 0 {
+			dlam12 = -2 * g.f1 * dn1 / sbet1
+		} else {
+			_, dlam12, _, _, _ = g.lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2,
+				cbet1, cbet2, C1a, C2a)
+			dlam12 *= g.f1 / (calp2 * cbet2)
+		}
+	} else {
+		dlam12 = math.NaN()
+	}
+	return
+}
+
+// Solve the inverse geodesic problem between (lat1, lon1) and (lat2,
+// lon2). Returns the arc length a12 (degrees), the distance s12
+// (meters), the sines and cosines of the azimuths at both ends and,
+// if area is set, the area S12 (square meters) between the geodesic
+// and the equator.
+func (g *geodesic) genInverse(lat1, lon1, lat2, lon2 float64, area bool) (a12, s12, salp1, calp1, salp2, calp2, S12 float64) {
+	lon12, lon12s := angDiff(lon1, lon2)
+	// Make longitude difference positive
+	lonsign := 1.0
+	if lon12 < 0 {
+		lonsign = -1
+	}
+	// If very close to being on the same half-meridian, then make it so
+	lon12 = lonsign * angRound(lon12)
+	lon12s = angRound((180 - lon12) - lonsign*lon12s)
+	lam12 := rad(lon12)
+	var slam12, clam12 float64
+	if lon12 > 90 {
+		slam12, clam12 = sincosd(lon12s)
+		clam12 = -clam12
+	} else {
+		slam12, clam12 = sincosd(lon12)
+	}
+
+	// If really close to the equator, treat as on equator
+	lat1 = angRound(latFix(lat1))
+	lat2 = angRound(latFix(lat2))
+
+	// Swap points so that point with higher (abs) latitude is point 1
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) {
+		swapp = -1
+		lonsign *= -1
+		lat1, lat2 = lat2, lat1
+	}
+	// Make lat1 <= 0
+	latsign := -1.0
+	if lat1 < 0 {
+		latsign = 1
+	}
+	lat1 *= latsign
+	lat2 *= latsign
+
+	sbet1, cbet1 := sincosd(lat1)
+	sbet1 *= g.f1
+	sbet1, cbet1 = norm2(sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	sbet2, cbet2 := sincosd(lat2)
+	sbet2 *= g.f1
+	sbet2, cbet2 = norm2(sbet2, cbet2)
+	cbet2 = math.Max(tiny, cbet2)
+
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			sbet2 = math.Copysign(sbet1, sbet2)
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+
+	dn1 := math.Sqrt(1 + g.ep2*sq(sbet1))
+	dn2 := math.Sqrt(1 + g.ep2*sq(sbet2))
+
+	C1a := make([]float64, nC1+1)
+	C2a := make([]float64, nC2+1)
+	C3a := make([]float64, nC3)
+
+	var sig12, s12x, m12x, omg12 float64
+	somg12, comg12 := 2.0, 0.0 // somg12 > 1 marks omg12 as not yet evaluated
+
+	meridian := lat1 == -90 || slam12 == 0
+	if meridian {
+		// Endpoints are on a single full meridian
+		calp1, salp1 = clam12, slam12
+		calp2, salp2 = 1, 0
+
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+
+		sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+		s12x, m12x, _, _, _ = g.lengths(g.n, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2,
+			cbet1, cbet2, C1a, C2a)
+		// Unless the meridian is a shortest path, fall through to the general case
+		if sig12 < 1 || m12x >= 0 {
+			if sig12 < 3*tiny {
+				sig12, m12x, s12x = 0, 0, 0
+			}
+			m12x *= g.b
+			s12x *= g.b
+			a12 = deg(sig12)
+		} else {
+			meridian = false
+		}
+	}
+
+	if !meridian && sbet1 == 0 && (g.f <= 0 || lon12s >= g.f*180) {
+		// Geodesic runs along the equator
+		calp1, calp2 = 0, 0
+		salp1, salp2 = 1, 1
+		s12x = g.a * lam12
+		sig12 = lam12 / g.f1
+		omg12 = sig12
+		m12x = g.b * math.Sin(sig12)
+		a12 = lon12 / g.f1
+	} else if !meridian {
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = g.inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2,
+			lam12, slam12, clam12, C1a, C2a)
+
+		if sig12 >= 0 {
+			// Short lines, inverseStart already solved the problem
+			s12x = sig12 * g.b * dnm
+			m12x = sq(dnm) * g.b * math.Sin(sig12/dnm)
+			a12 = deg(sig12)
+			omg12 = lam12 / (g.f1 * dnm)
+		} else {
+			// Newton's method, falling back to bisection when it strays
+			var ssig1, csig1, ssig2, csig2, eps, domg12 float64
+			tripn, tripb := false, false
+			salp1a, calp1a := tiny, 1.0
+			salp1b, calp1b := tiny, -1.0
+			for numit := 0; numit < maxit2; numit++ {
+				var v, dv float64
+				v, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps, domg12, dv = g.lambda12(
+					sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam12, clam12,
+					numit < maxit1, C1a, C2a, C3a)
+
+				// Reversed test to allow escape with NaNs
+				lim := 1.0
+				if tripn {
+					lim = 8
+				}
+				if tripb || !(math.Abs(v) >= lim*tol0) {
+					break
+				}
+
+				// Update bracketing values
+				if v > 0 && (numit > maxit1 || calp1/salp1 > calp1b/salp1b) {
+					salp1b, calp1b = salp1, calp1
+				} else if v < 0 && (numit > maxit1 || calp1/salp1 < calp1a/salp1a) {
+					salp1a, calp1a = salp1, calp1
+				}
+
+				if numit+1 < maxit1 && dv > 0 {
+					dalp1 := -v / dv
+					sdalp1, cdalp1 := math.Sin(dalp1), math.Cos(dalp1)
+					nsalp1 := salp1*cdalp1 + calp1*sdalp1
+					if nsalp1 > 0 && math.Abs(dalp1) < math.Pi {
+						calp1 = calp1*cdalp1 - salp1*sdalp1
+						salp1 = nsalp1
+						salp1, calp1 = norm2(salp1, calp1)
+						tripn = math.Abs(v) <= 16*tol0
+						continue
+					}
+				}
+
+				// Newton's step was unusable, bisect the bracket instead
+				salp1 = (salp1a + salp1b) / 2
+				calp1 = (calp1a + calp1b) / 2
+				salp1, calp1 = norm2(salp1, calp1)
+				tripn = false
+				tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < tolb ||
+					math.Abs(salp1-salp1b)+(calp1-calp1b) < tolb
+			}
+
+			s12x, m12x, _, _, _ = g.lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2,
+				cbet1, cbet2, C1a, C2a)
+			m12x *= g.b
+			s12x *= g.b
+			a12 = deg(sig12)
+
+			if area {
+				sdomg12, cdomg12 := math.Sin(domg12), math.Cos(domg12)
+				somg12 = slam12*cdomg12 - clam12*sdomg12
+				comg12 = clam12*cdomg12 + slam12*sdomg12
+			}
+		}
+	}
+
+	s12 = 0 + s12x
+
+	if area {
+		salp0 := salp1 * cbet1
+		calp0 := math.Hypot(calp1, salp1*sbet1)
+		if calp0 != 0 && salp0 != 0 {
+			ssig1, csig1 := norm2(sbet1, calp1*cbet1)
+			ssig2, csig2 := norm2(sbet2, calp2*cbet2)
+			k2 := sq(calp0) * g.ep2
+			eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+			A4 := sq(g.a) * calp0 * salp0 * g.e2
+			C4a := make([]float64, nC4)
+			g.c4f(eps, C4a)
+			B41 := sinCosSeries(false, ssig1, csig1, C4a)
+			B42 := sinCosSeries(false, ssig2, csig2, C4a)
+			S12 = A4 * (B42 - B41)
+		}
+
+		if !meridian && somg12 > 1 {
+			somg12, comg12 = math.Sin(omg12), math.Cos(omg12)
+		}
+
+		var alp12 float64
+		if !meridian && comg12 > -0.7071 && sbet2-sbet1 < 1.75 {
+			// Use tan(Gamma/2) = tan(omg12/2) * (tan(bet1/2)+tan(bet2/2))/(1+tan(bet1/2)*tan(bet2/2))
+			domg12 := 1 + comg12
+			dbet1 := 1 + cbet1
+			dbet2 := 1 + cbet2
+			alp12 = 2 * math.Atan2(somg12*(sbet1*dbet2+sbet2*dbet1), domg12*(sbet1*sbet2+dbet1*dbet2))
+		} else {
+			salp12 := salp2*calp1 - calp2*salp1
+			calp12 := calp2*calp1 + salp2*salp1
+			if salp12 == 0 && calp12 < 0 {
+				salp12 = tiny * calp1
+				calp12 = -1
+			}
+			alp12 = math.Atan2(salp12, calp12)
+		}
+		S12 += g.c2 * alp12
+		S12 *= swapp * lonsign * latsign
+		S12 += 0
+	}
+
+	// Undo the swaps made above
+	if swapp < 0 {
+		salp1, salp2 = salp2, salp1
+		calp1, calp2 = calp2, calp1
+	}
+	salp1 *= swapp * lonsign
+	calp1 *= swapp * latsign
+	salp2 *= swapp * lonsign
+	calp2 *= swapp * latsign
+	return
+}
+
+// Solve the inverse geodesic problem, returning the distance s12 in
+// meters and the forward azimuths azi1 and azi2 (degrees clockwise
+// from north) at both ends
+func (g *geodesic) inverse(lat1, lon1, lat2, lon2 float64) (s12, azi1, azi2 float64) {
+	_, s12, salp1, calp1, salp2, calp2, _ := g.genInverse(lat1, lon1, lat2, lon2, false)
+	return s12, atan2d(salp1, calp1), atan2d(salp2, calp2)
+}
+
+// Solve the direct geodesic problem: starting from (lat1, lon1) with
+// azimuth azi1, travel s12 meters. Returns the destination and the
+// forward azimuth there.
+func (g *geodesic) direct(lat1, lon1, azi1, s12 float64) (lat2, lon2, azi2 float64) {
+	lat1 = latFix(lat1)
+	salp1, calp1 := sincosd(angRound(azi1))
+
+	sbet1, cbet1 := sincosd(angRound(lat1))
+	sbet1 *= g.f1
+	sbet1, cbet1 = norm2(sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	ssig1 := sbet1
+	somg1 := salp0 * sbet1
+	csig1 := 1.0
+	if sbet1 != 0 || calp1 != 0 {
+		csig1 = cbet1 * calp1
+	}
+	comg1 := csig1
+	ssig1, csig1 = norm2(ssig1, csig1)
+
+	k2 := sq(calp0) * g.ep2
+	eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+
+	A1m1 := a1m1f(eps)
+	C1a := make([]float64, nC1+1)
+	c1f(eps, C1a)
+	B11 := sinCosSeries(true, ssig1, csig1, C1a)
+	s, c := math.Sin(B11), math.Cos(B11)
+	stau1 := ssig1*c + csig1*s
+	ctau1 := csig1*c - ssig1*s
+
+	C1pa := make([]float64, nC1p+1)
+	c1pf(eps, C1pa)
+
+	C3a := make([]float64, nC3)
+	g.c3f(eps, C3a)
+	A3c := -g.f * salp0 * g.a3f(eps)
+	B31 := sinCosSeries(true, ssig1, csig1, C3a)
+
+	// Convert the distance to an arc length on the auxiliary sphere
+	tau12 := s12 / (g.b * (1 + A1m1))
+	s, c = math.Sin(tau12), math.Cos(tau12)
+	B12 := -sinCosSeries(true, stau1*c+ctau1*s, ctau1*c-stau1*s, C1pa)
+	sig12 := tau12 - (B12 - B11)
+	ssig12, csig12 := math.Sin(sig12), math.Cos(sig12)
+	if math.Abs(g.f) > 0.01 {
+		// The reverted series is not accurate enough for very eccentric
+		// ellipsoids, so take one Newton step
+		ssig2 := ssig1*csig12 + csig1*ssig12
+		csig2 := csig1*csig12 - ssig1*ssig12
+		B12 = sinCosSeries(true, ssig2, csig2, C1a)
+		serr := (1+A1m1)*(sig12+(B12-B11)) - s12/g.b
+		sig12 -= serr / math.Sqrt(1+k2*sq(ssig2))
+		ssig12, csig12 = math.Sin(sig12), math.Cos(sig12)
+	}
+
+	ssig2 := ssig1*csig12 + csig1*ssig12
+	csig2 := csig1*csig12 - ssig1*ssig12
+	sbet2 := calp0 * ssig2
+	cbet2 := math.Hypot(salp0, calp0*csig2)
+	if cbet2 == 0 {
+		// The geodesic ends at a pole
+		cbet2 = tiny
+		csig2 = tiny
+	}
+	salp2, calp2 := salp0, calp0*csig2
+
+	somg2, comg2 := salp0*ssig2, csig2
+	omg12 := math.Atan2(somg2*comg1-comg2*somg1, comg2*comg1+somg2*somg1)
+	lam12 := omg12 + A3c*(sig12+(sinCosSeries(true, ssig2, csig2, C3a)-B31))
+
+	lat2 = atan2d(sbet2, g.f1*cbet2)
+	lon2 = angNormalize(angNormalize(lon1) + angNormalize(deg(lam12)))
+	azi2 = atan2d(salp2, calp2)
+	return
+}
@@ -0,0 +1,742 @@
+package latlong
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between floating point values
+)
+
+// Known WGS84 geodesics from Karney (2013) and the GeographicLib
+// documentation, including a nearly antipodal pair on which Vincenty's
+// method fails to converge.
+func TestGeodesicDistance(t *testing.T) {
+	cases := []struct {
+		a, b   Coordinate
+		meters float64
+	}{
+		{Coordinate{Latitude: -41.32, Longitude: 174.81}, Coordinate{Latitude: 40.96, Longitude: -5.50}, 19959679.267},
+		{Coordinate{Latitude: 40.6, Longitude: -73.8}, Coordinate{Latitude: 51.6, Longitude: -0.5}, 5551759.400},
+		{Coordinate{Latitude: 0, Longitude: 0}, Coordinate{Latitude: 0.5, Longitude: 179.5}, 19936288.579},
+		{Coordinate{Latitude: 0, Longitude: 0}, Coordinate{Latitude: 90, Longitude: 0}, 10001965.729},
+	}
+
+	for _, c := range cases {
+		got := GeodesicDistance(c.a, c.b).Meters()
+		if d := math.Abs(got - c.meters); d > 0.001 {
+			t.Errorf("Distance from %v to %v was %f m, wanted %f m", c.a, c.b, got, c.meters)
+		}
+	}
+}
+
+// Generate 100,000 random pairs of lat/long coordinates and assert
+// that the ellipsoidal distance stays within 0.6% of the spherical
+// one.
+func TestRandGeodesicDistance(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		a := Coordinate{
+			Latitude:  -90 + rand.Float64()*180,
+			Longitude: -180 + rand.Float64()*360,
+		}
+		b := Coordinate{
+			Latitude:  -90 + rand.Float64()*180,
+			Longitude: -180 + rand.Float64()*360,
+		}
+
+		want := Distance(a, b)
+		got := GeodesicDistance(a, b)
+		if d := math.Abs(float64(want - got)); d > 0.006*float64(want)+closeEnough {
+			t.Errorf("Geodesic distance (%f) too far from spherical distance (%f)", got, want)
+			t.FailNow()
+		}
+	}
+}
+
+// Check the derived parameters of the predefined ellipsoids and that
+// user-defined ellipsoids are validated.
+func TestEllipsoids(t *testing.T) {
+	if b := WGS84.SemiMinorAxis(); math.Abs(b-6356752.314245) > 0.000001 {
+		t.Errorf("WGS84 semi-minor axis was %f, wanted 6356752.314245", b)
+	}
+	if b := Clarke1866.SemiMinorAxis(); math.Abs(b-6356583.8) > 0.001 {
+		t.Errorf("Clarke 1866 semi-minor axis was %f, wanted 6356583.8", b)
+	}
+
+	if e, err := LookupEllipsoid("airy1830"); err != nil || e != Airy1830 {
+		t.Errorf("Lookup of airy1830 failed: %v", err)
+	}
+	if _, err := LookupEllipsoid("Bessel1841"); err == nil {
+		t.Error("Lookup of an unknown ellipsoid should fail")
+	}
+
+	if _, err := NewEllipsoid("flat", 6378137, 0.5); err == nil {
+		t.Error("Inverse flattening below 1 should be rejected")
+	}
+	sphere, err := NewEllipsoid("sphere", Miles(3958.76).Meters(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := Coordinate{Latitude: 10, Longitude: 20}, Coordinate{Latitude: -30, Longitude: 40}
+	if got, want := sphere.Distance(a, b), Distance(a, b); math.Abs(float64(got-want)) > 0.001 {
+		t.Errorf("Distance on a sphere (%f) should match haversine distance (%f)", got, want)
+	}
+}
+
+// Check conversions between units of length.
+func TestLength(t *testing.T) {
+	cases := []struct {
+		got, want float64
+	}{
+		{Miles(1).Meters(), 1609.344},
+		{Kilometers(1.5).Meters(), 1500},
+		{NauticalMiles(1).Kilometers(), 1.852},
+		{Feet(5280).Miles(), 1},
+		{Meters(1852).NauticalMiles(), 1},
+		{Miles(3).Feet(), 15840},
+	}
+	for _, c := range cases {
+		if d := math.Abs(c.got - c.want); d > closeEnough {
+			t.Errorf("Converted length was %f, wanted %f", c.got, c.want)
+		}
+	}
+
+	for _, s := range []string{"km", "Kilometers"} {
+		u, err := ParseUnit(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Miles(1).In(u); math.Abs(got-1.609344) > closeEnough {
+			t.Errorf("One mile was %f %s, wanted 1.609344", got, u.Name)
+		}
+	}
+	if _, err := ParseUnit("furlongs"); err == nil {
+		t.Error("Parsing an unknown unit should fail")
+	}
+}
+
+// Check bearings on the sphere and the WGS84 ellipsoid against known
+// values, including the GeographicLib JFK to LHR example.
+func TestBearings(t *testing.T) {
+	jfk := Coordinate{Latitude: 40.6, Longitude: -73.8}
+	lhr := Coordinate{Latitude: 51.6, Longitude: -0.5}
+	cases := []struct {
+		got, want float64
+	}{
+		{InitialBearing(Coordinate{Latitude: 0, Longitude: 0}, Coordinate{Latitude: 0, Longitude: 10}), 90},
+		{InitialBearing(Coordinate{Latitude: 0, Longitude: 0}, Coordinate{Latitude: -10, Longitude: 0}), 180},
+		{FinalBearing(Coordinate{Latitude: 0, Longitude: 10}, Coordinate{Latitude: 0, Longitude: 0}), 270},
+		{InitialBearing(Coordinate{Latitude: 10, Longitude: 179}, Coordinate{Latitude: 10, Longitude: -179}), 89.826335},
+		{WGS84.InitialBearing(jfk, lhr), 51.198882845},
+		{WGS84.FinalBearing(jfk, lhr), 107.821776735},
+		{WGS84.InitialBearing(lhr, jfk), 287.821776735},
+	}
+	for _, c := range cases {
+		if d := math.Abs(c.got - c.want); d > 0.000001 {
+			t.Errorf("Bearing was %f, wanted %f", c.got, c.want)
+		}
+	}
+}
+
+// Fly from 100,000 random coordinates on random bearings, then assert
+// that the inverse problem recovers the distance and bearing flown.
+func TestRandDestination(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		start := Coordinate{
+			Latitude:  -89 + rand.Float64()*178,
+			Longitude: -180 + rand.Float64()*360,
+		}
+		bearing := rand.Float64() * 360
+		distance := Kilometers(1 + rand.Float64()*15000)
+
+		got := Destination(start, bearing, distance)
+		if d := math.Abs(float64(Distance(start, got) - distance)); d > 0.001 {
+			t.Errorf("Spherical destination is %f m off the distance flown", d)
+			t.FailNow()
+		}
+		if d := math.Abs(angNormalize(InitialBearing(start, got) - bearing)); d > 0.000001 {
+			t.Errorf("Spherical destination is %f degrees off the bearing flown", d)
+			t.FailNow()
+		}
+
+		got = WGS84.Destination(start, bearing, distance)
+		if d := math.Abs(float64(WGS84.Distance(start, got) - distance)); d > 0.001 {
+			t.Errorf("Geodesic destination is %f m off the distance flown", d)
+			t.FailNow()
+		}
+		if d := math.Abs(angNormalize(WGS84.InitialBearing(start, got) - bearing)); d > 0.000001 {
+			t.Errorf("Geodesic destination is %f degrees off the bearing flown", d)
+			t.FailNow()
+		}
+	}
+}
+
+// Check cross-track and along-track distances to a route along the
+// equator, on both sides of course and beyond its ends.
+func TestRouteDeviation(t *testing.T) {
+	route := Route{
+		Coordinate{Latitude: 0, Longitude: 0},
+		Coordinate{Latitude: 0, Longitude: 10},
+		Coordinate{Latitude: 10, Longitude: 10},
+	}
+	degree := earthRadius * math.Pi / 180
+
+	cases := []struct {
+		point      Coordinate
+		crossTrack Length
+		alongTrack Length
+		leg        int
+	}{
+		{Coordinate{Latitude: 1, Longitude: 5}, -1 * degree, 5 * degree, 0},
+		{Coordinate{Latitude: -1, Longitude: 5}, 1 * degree, 5 * degree, 0},
+		{Coordinate{Latitude: 5, Longitude: 11}, earthRadius * Length(math.Asin(math.Cos(rad(5))*math.Sin(rad(1)))),
+			10*degree + earthRadius*Length(math.Atan(math.Tan(rad(5))/math.Cos(rad(1)))), 1},
+		{Coordinate{Latitude: 0, Longitude: -2}, 2 * degree, 0, 0},
+	}
+	for _, c := range cases {
+		got := route.Deviation(c.point)
+		if d := math.Abs(float64(got.CrossTrack - c.crossTrack)); d > 1 {
+			t.Errorf("Cross-track distance of %v was %f, wanted %f", c.point, got.CrossTrack, c.crossTrack)
+		}
+		if d := math.Abs(float64(got.AlongTrack - c.alongTrack)); d > 1 {
+			t.Errorf("Along-track distance of %v was %f, wanted %f", c.point, got.AlongTrack, c.alongTrack)
+		}
+		if got.Leg != c.leg {
+			t.Errorf("Nearest leg to %v was %d, wanted %d", c.point, got.Leg, c.leg)
+		}
+	}
+
+	if d := math.Abs(float64(route.Length() - 20*degree)); d > closeEnough {
+		t.Errorf("Route length was %f, wanted %f", route.Length(), 20*degree)
+	}
+}
+
+// Check rhumb lines against great circles where they coincide, and
+// fly 100,000 random rhumb lines, asserting that the distance and
+// bearing flown are recovered.
+func TestRhumb(t *testing.T) {
+	a, b := Coordinate{Latitude: 0, Longitude: 170}, Coordinate{Latitude: 0, Longitude: -170}
+	if d := math.Abs(float64(RhumbDistance(a, b) - Distance(a, b))); d > 0.001 {
+		t.Errorf("Rhumb line along the equator is %f m off the great circle", d)
+	}
+	if got := RhumbBearing(a, b); math.Abs(got-90) > closeEnough {
+		t.Errorf("Rhumb bearing along the equator was %f, wanted 90", got)
+	}
+
+	a, b = Coordinate{Latitude: 50, Longitude: 0}, Coordinate{Latitude: 50, Longitude: 10}
+	if RhumbDistance(a, b) <= Distance(a, b) {
+		t.Error("Rhumb line along a parallel should be longer than the great circle")
+	}
+
+	for i := 0; i < 100000; i++ {
+		start := Coordinate{
+			Latitude:  -80 + rand.Float64()*160,
+			Longitude: -180 + rand.Float64()*360,
+		}
+		bearing := rand.Float64() * 360
+		distance := Kilometers(1 + rand.Float64()*1000)
+
+		got := RhumbDestination(start, bearing, distance)
+		if math.Abs(got.Latitude) == 90 {
+			// The line must have headed for that pole, and been long
+			// enough to reach it
+			northing := distance * Length(math.Cos(rad(bearing)))
+			toPole := Length(rad(got.Latitude-start.Latitude)) * earthRadius
+			if math.Signbit(float64(northing)) != math.Signbit(float64(toPole)) || math.Abs(float64(northing)) < math.Abs(float64(toPole)) {
+				t.Fatalf("Flying %v from %v at %f stopped at the pole", distance, start, bearing)
+			}
+			continue
+		}
+		if d := math.Abs(float64(RhumbDistance(start, got) - distance)); d > 0.001 {
+			t.Errorf("Rhumb destination is %f m off the distance flown", d)
+			t.FailNow()
+		}
+		if d := math.Abs(angNormalize(RhumbBearing(start, got) - bearing)); d > 0.000001 {
+			t.Errorf("Rhumb destination is %f degrees off the bearing flown", d)
+			t.FailNow()
+		}
+	}
+}
+
+// Fly rhumb lines over and into the poles.
+func TestRhumbPoles(t *testing.T) {
+	cases := []struct {
+		start    Coordinate
+		bearing  float64
+		distance Length
+		want     Coordinate
+	}{
+		// Due north over the pole comes down the opposite meridian
+		{Coordinate{Latitude: 80, Longitude: 10}, 0, Length(rad(20)) * earthRadius, Coordinate{Latitude: 80, Longitude: -170}},
+		{Coordinate{Latitude: -85, Longitude: -100}, 180, Length(rad(15)) * earthRadius, Coordinate{Latitude: -80, Longitude: 80}},
+		// Right round the earth along a meridian
+		{Coordinate{Latitude: 0, Longitude: 0}, 0, Length(rad(360)) * earthRadius, Coordinate{Latitude: 0, Longitude: 0}},
+		// Any other heading spirals into the pole and stops there
+		{Coordinate{Latitude: 89, Longitude: 30}, 45, Length(rad(5)) * earthRadius, Coordinate{Latitude: 90, Longitude: 30}},
+		{Coordinate{Latitude: -60, Longitude: 30}, 200, Length(rad(90)) * earthRadius, Coordinate{Latitude: -90, Longitude: 30}},
+	}
+	for _, c := range cases {
+		got := RhumbDestination(c.start, c.bearing, c.distance)
+		if math.Abs(got.Latitude-c.want.Latitude) > closeEnough || math.Abs(angNormalize(got.Longitude-c.want.Longitude)) > closeEnough {
+			t.Errorf("Flying %v from %v at %f reached %v, wanted %v", c.distance, c.start, c.bearing, got, c.want)
+		}
+	}
+}
+
+// Check polygon areas and perimeters against exact values for an
+// octant of the earth, in both winding orders.
+func TestPolygonArea(t *testing.T) {
+	octant := Polygon{
+		Coordinate{Latitude: 0, Longitude: 0},
+		Coordinate{Latitude: 0, Longitude: 90},
+		Coordinate{Latitude: 90, Longitude: 0},
+	}
+	reversed := Polygon{octant[2], octant[1], octant[0]}
+	r := earthRadius.Meters()
+
+	for _, p := range []Polygon{octant, reversed} {
+		if d := math.Abs(p.Area() - math.Pi*r*r/2); d > 1 {
+			t.Errorf("Spherical octant area was %f, wanted %f", p.Area(), math.Pi*r*r/2)
+		}
+		if d := math.Abs(float64(p.Perimeter()) - 3*math.Pi*r/2); d > 0.001 {
+			t.Errorf("Spherical octant perimeter was %f, wanted %f", p.Perimeter(), 3*math.Pi*r/2)
+		}
+		// The surface of the WGS84 ellipsoid is 510065621724088 square meters
+		if d := math.Abs(WGS84.PolygonArea(p) - 510065621724088.5/8); d > 1 {
+			t.Errorf("WGS84 octant area was %f, wanted %f", WGS84.PolygonArea(p), 510065621724088.5/8)
+		}
+		// Two quarter meridians and a quarter of the equator
+		perimeter := 2*10001965.729313 + math.Pi*WGS84.SemiMajorAxis()/2
+		if d := math.Abs(float64(WGS84.PolygonPerimeter(p)) - perimeter); d > 0.001 {
+			t.Errorf("WGS84 octant perimeter was %f, wanted %f", WGS84.PolygonPerimeter(p), perimeter)
+		}
+	}
+
+	square := Polygon{
+		Coordinate{Latitude: 0, Longitude: 0},
+		Coordinate{Latitude: 0, Longitude: 1},
+		Coordinate{Latitude: 1, Longitude: 1},
+		Coordinate{Latitude: 1, Longitude: 0},
+	}
+	if d := math.Abs(WGS84.PolygonArea(square) - 12308778361.469); d > 0.01 {
+		t.Errorf("WGS84 area of a one degree square was %f, wanted 12308778361.469", WGS84.PolygonArea(square))
+	}
+}
+
+// Check point-in-polygon tests for polygons that straddle the
+// antimeridian and that surround a pole, in both winding orders.
+func TestPolygonContains(t *testing.T) {
+	pacific := Polygon{
+		Coordinate{Latitude: -10, Longitude: 170},
+		Coordinate{Latitude: -10, Longitude: -170},
+		Coordinate{Latitude: 10, Longitude: -170},
+		Coordinate{Latitude: 10, Longitude: 170},
+	}
+	arctic := Polygon{
+		Coordinate{Latitude: 80, Longitude: 0},
+		Coordinate{Latitude: 80, Longitude: 90},
+		Coordinate{Latitude: 80, Longitude: 180},
+		Coordinate{Latitude: 80, Longitude: -90},
+	}
+	cases := []struct {
+		polygon Polygon
+		point   Coordinate
+		want    bool
+	}{
+		{pacific, Coordinate{Latitude: 0, Longitude: 180}, true},
+		{pacific, Coordinate{Latitude: 5, Longitude: -175}, true},
+		{pacific, Coordinate{Latitude: 5, Longitude: 175}, true},
+		{pacific, Coordinate{Latitude: 0, Longitude: 0}, false},
+		{pacific, Coordinate{Latitude: 0, Longitude: 160}, false},
+		{pacific, Coordinate{Latitude: 20, Longitude: 180}, false},
+		{arctic, Coordinate{Latitude: 90, Longitude: 0}, true},
+		{arctic, Coordinate{Latitude: 85, Longitude: 45}, true},
+		{arctic, Coordinate{Latitude: 84, Longitude: -120}, true},
+		{arctic, Coordinate{Latitude: 81, Longitude: -120}, false}, // Edges bulge poleward of 80 degrees
+		{arctic, Coordinate{Latitude: 0, Longitude: 0}, false},
+		{arctic, Coordinate{Latitude: 75, Longitude: 10}, false},
+		{arctic, Coordinate{Latitude: -90, Longitude: 0}, false},
+	}
+	for _, c := range cases {
+		reversed := make(Polygon, len(c.polygon))
+		for i, v := range c.polygon {
+			reversed[len(reversed)-1-i] = v
+		}
+		if got := c.polygon.Contains(c.point); got != c.want {
+			t.Errorf("Contains(%v) was %t, wanted %t", c.point, got, c.want)
+		}
+		if got := reversed.Contains(c.point); got != c.want {
+			t.Errorf("Contains(%v) on reversed polygon was %t, wanted %t", c.point, got, c.want)
+		}
+	}
+}
+
+// Check bounding boxes of point sets on either side of and across the
+// antimeridian, and their operations.
+func TestBoundingBox(t *testing.T) {
+	pacific, err := NewBoundingBox([]LatLonger{
+		Coordinate{Latitude: 10, Longitude: 179.5},
+		Coordinate{Latitude: 11, Longitude: -179.5},
+		Coordinate{Latitude: 12, Longitude: -178.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BoundingBox{South: 10, North: 12, West: 179.5, East: -178.5}); pacific != want {
+		t.Errorf("Pacific box was %+v, wanted %+v", pacific, want)
+	}
+	if !pacific.CrossesAntimeridian() {
+		t.Errorf("Pacific box %+v does not cross the antimeridian", pacific)
+	}
+	atlantic, _ := NewBoundingBox([]LatLonger{
+		Coordinate{Latitude: 40.6, Longitude: -73.8},
+		Coordinate{Latitude: 51.6, Longitude: -0.5},
+	})
+	if want := (BoundingBox{South: 40.6, North: 51.6, West: -73.8, East: -0.5}); atlantic != want {
+		t.Errorf("Atlantic box was %+v, wanted %+v", atlantic, want)
+	}
+	if _, err := NewBoundingBox(nil); err == nil {
+		t.Errorf("Bounding no points succeeded")
+	}
+
+	contains := []struct {
+		point Coordinate
+		want  bool
+	}{
+		{Coordinate{Latitude: 11, Longitude: 180}, true},
+		{Coordinate{Latitude: 11, Longitude: -180}, true},
+		{Coordinate{Latitude: 11, Longitude: 179.9}, true},
+		{Coordinate{Latitude: 11, Longitude: 0}, false},
+		{Coordinate{Latitude: 11, Longitude: 179}, false},
+		{Coordinate{Latitude: 13, Longitude: 180}, false},
+	}
+	for _, c := range contains {
+		if got := pacific.Contains(c.point); got != c.want {
+			t.Errorf("Contains(%v) was %t, wanted %t", c.point, got, c.want)
+		}
+	}
+
+	intersects := []struct {
+		a, b BoundingBox
+		want bool
+	}{
+		{pacific, BoundingBox{South: 0, North: 20, West: 170, East: 179.6}, true},
+		{pacific, BoundingBox{South: 0, North: 20, West: -179, East: -170}, true},
+		{pacific, BoundingBox{South: 0, North: 20, West: 170, East: 179}, false},
+		{pacific, BoundingBox{South: 12.5, North: 20, West: 170, East: -170}, false},
+		{pacific, atlantic, false},
+		{pacific, BoundingBox{South: -90, North: 90, West: -180, East: 180}, true},
+	}
+	for _, c := range intersects {
+		if got := c.a.Intersects(c.b); got != c.want {
+			t.Errorf("%+v Intersects(%+v) was %t, wanted %t", c.a, c.b, got, c.want)
+		}
+		if got := c.b.Intersects(c.a); got != c.want {
+			t.Errorf("%+v Intersects(%+v) was %t, wanted %t", c.b, c.a, got, c.want)
+		}
+	}
+
+	unions := []struct {
+		a, b, want BoundingBox
+	}{
+		{pacific, atlantic, BoundingBox{South: 10, North: 51.6, West: 179.5, East: -0.5}},
+		{pacific, BoundingBox{South: 0, North: 1, West: 170, East: 175}, BoundingBox{South: 0, North: 12, West: 170, East: -178.5}},
+		{pacific, BoundingBox{South: 0, North: 1, West: 179, East: -179}, BoundingBox{South: 0, North: 12, West: 179, East: -178.5}},
+		{atlantic, BoundingBox{South: 0, North: 1, West: 0, East: 10}, BoundingBox{South: 0, North: 51.6, West: -73.8, East: 10}},
+		{BoundingBox{West: 0, East: 170}, BoundingBox{West: 175, East: 5}, BoundingBox{West: 175, East: 170}},
+		{BoundingBox{West: 0, East: 170}, BoundingBox{West: 170, East: 0}, BoundingBox{West: -180, East: 180}},
+	}
+	for _, c := range unions {
+		if got := c.a.Union(c.b); got != c.want {
+			t.Errorf("%+v Union(%+v) was %+v, wanted %+v", c.a, c.b, got, c.want)
+		}
+		if got := c.b.Union(c.a); got != c.want {
+			t.Errorf("%+v Union(%+v) was %+v, wanted %+v", c.b, c.a, got, c.want)
+		}
+	}
+
+	// A point 100 km away in any direction lands inside the expanded
+	// box and outside the original one
+	expanded := pacific.Expand(Kilometers(100))
+	if !expanded.CrossesAntimeridian() {
+		t.Errorf("Expanded box %+v does not cross the antimeridian", expanded)
+	}
+	for _, corner := range []Coordinate{{Latitude: 10, Longitude: 179.5}, {Latitude: 12, Longitude: -178.5}} {
+		for bearing := 0.0; bearing < 360; bearing += 15 {
+			p := Destination(corner, bearing, Kilometers(99.9))
+			if !expanded.Contains(p) {
+				t.Errorf("Expanded box %+v does not contain %v", expanded, p)
+			}
+		}
+	}
+	if polar := pacific.Expand(Kilometers(9000)); polar.North != 90 || polar.West != -180 || polar.East != 180 {
+		t.Errorf("Box expanded over the pole was %+v", polar)
+	}
+}
+
+// Parse coordinates written the ways field reports write them.
+func TestParseCoordinate(t *testing.T) {
+	cases := []struct {
+		s                   string
+		latitude, longitude float64
+	}{
+		{`40°26'46.3"N 79°58'56"W`, 40 + 26.0/60 + 46.3/3600, -(79 + 58.0/60 + 56.0/3600)},
+		{`40 26.772N 79 58.933W`, 40 + 26.772/60, -(79 + 58.933/60)},
+		{`N40 26.772 W79 58.933`, 40 + 26.772/60, -(79 + 58.933/60)},
+		{`79°58′56″W 40°26′46″N`, 40 + 26.0/60 + 46.0/3600, -(79 + 58.0/60 + 56.0/3600)},
+		{`40.446195, -79.982222`, 40.446195, -79.982222},
+		{`-33 51 35.9 151 12 40`, -(33 + 51.0/60 + 35.9/3600), 151 + 12.0/60 + 40.0/3600},
+		{`-0 30, 0 30`, -0.5, 0.5},
+		{`41.32s 174.81e`, -41.32, 174.81},
+	}
+	for _, c := range cases {
+		got, err := ParseCoordinate(c.s)
+		if err != nil {
+			t.Errorf("ParseCoordinate(%s) failed: %s", c.s, err)
+			continue
+		}
+		if math.Abs(got.Latitude-c.latitude) > closeEnough || math.Abs(got.Longitude-c.longitude) > closeEnough {
+			t.Errorf("ParseCoordinate(%s) was %v, wanted {%f %f}", c.s, got, c.latitude, c.longitude)
+		}
+	}
+
+	bad := []string{
+		``,
+		`40.5`,
+		`40 26 46 79 58`,
+		`40°26'46"N 79°58'56"N`,
+		`-40°26'46"N 79°58'56"W`,
+		`40°61'N 79°58'W`,
+		`40.5 26N 79W`,
+		`91N 79W`,
+		`40N 181W`,
+		`40N 79W 12`,
+		`forty north`,
+	}
+	for _, s := range bad {
+		if c, err := ParseCoordinate(s); err == nil {
+			t.Errorf("ParseCoordinate(%s) was %v, wanted an error", s, c)
+		}
+	}
+}
+
+// Format random coordinates in every style and assert that parsing
+// them again gives back the coordinate, to within the precision
+// written.
+func TestRandFormat(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		c := Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+		}
+		for format, perDegree := range []float64{1, 60, 3600} {
+			for hemisphere := HemisphereSuffix; hemisphere <= HemisphereSign; hemisphere++ {
+				style := Style{Format: AngleFormat(format), Precision: rand.Intn(6), Hemisphere: hemisphere}
+				s := c.Format(style)
+				got, err := ParseCoordinate(s)
+				if err != nil {
+					t.Fatalf("Parsing %s failed: %s", s, err)
+				}
+				tolerance := 0.5/perDegree/math.Pow(10, float64(style.Precision)) + closeEnough
+				if math.Abs(got.Latitude-c.Latitude) > tolerance || math.Abs(got.Longitude-c.Longitude) > tolerance {
+					t.Errorf("Formatted %v as %s, which parses as %v", c, s, got)
+				}
+			}
+		}
+	}
+}
+
+// Check formatting, including rounding that carries into the minutes
+// and degrees.
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		c     Coordinate
+		style Style
+		want  string
+	}{
+		{Coordinate{Latitude: 40.446195, Longitude: -79.982222}, Style{DegreesMinutesSeconds, 1, HemisphereSuffix}, `40°26'46.3"N 79°58'56.0"W`},
+		{Coordinate{Latitude: 40.446195, Longitude: -79.982222}, Style{DegreesMinutes, 3, HemispherePrefix}, `N40°26.772' W79°58.933'`},
+		{Coordinate{Latitude: 40.446195, Longitude: -79.982222}, Style{Decimal, 4, HemisphereSign}, `40.4462° -79.9822°`},
+		{Coordinate{Latitude: 9.99999, Longitude: -0.00001}, Style{DegreesMinutesSeconds, 0, HemisphereSuffix}, `10°00'00"N 0°00'00"E`},
+		{Coordinate{Latitude: -33.5, Longitude: 151.25}, Style{DegreesMinutes, 0, HemisphereSign}, `-33°30' 151°15'`},
+	}
+	for _, c := range cases {
+		if got := c.c.Format(c.style); got != c.want {
+			t.Errorf("Format(%v, %+v) was %s, wanted %s", c.c, c.style, got, c.want)
+		}
+	}
+}
+
+// Marshal random coordinates, half of them with altitudes, to JSON and
+// text, write them as lines of a trip file, and assert that they
+// decode to exactly the original.
+func TestRandMarshal(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		want := Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+			Altitude:  Meters(float64(i%2) * (rand.Float64()*20000 - 500)),
+		}
+
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var id int
+		var field string
+		if _, err := fmt.Sscanf(fmt.Sprintf("%d\t%s", i, b), "%d\t%s", &id, &field); err != nil || field != string(b) {
+			t.Fatalf("%s does not fit on a line of a trip file", b)
+		}
+		var got Coordinate
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", b, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, b, got)
+		}
+
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = Coordinate{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", text, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, text, got)
+		}
+	}
+
+	if _, err := (Coordinate{Latitude: math.NaN()}).MarshalText(); err == nil {
+		t.Errorf("Marshaling NaN succeeded")
+	}
+	if b, _ := json.Marshal(Coordinate{Latitude: 1, Longitude: 2}); strings.Contains(string(b), " ") {
+		t.Errorf("Marshaled JSON %s contains spaces", b)
+	}
+}
+
+// Check normalization of longitudes that wrap around and latitudes
+// that run over a pole.
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		in, want Coordinate
+	}{
+		{Coordinate{Latitude: 40, Longitude: -80}, Coordinate{Latitude: 40, Longitude: -80}},
+		{Coordinate{Latitude: 0, Longitude: 180}, Coordinate{Latitude: 0, Longitude: -180}},
+		{Coordinate{Latitude: 0, Longitude: 540}, Coordinate{Latitude: 0, Longitude: -180}},
+		{Coordinate{Latitude: 10, Longitude: 190}, Coordinate{Latitude: 10, Longitude: -170}},
+		{Coordinate{Latitude: 10, Longitude: -370}, Coordinate{Latitude: 10, Longitude: -10}},
+		{Coordinate{Latitude: 100, Longitude: 0}, Coordinate{Latitude: 80, Longitude: -180}},
+		{Coordinate{Latitude: -100, Longitude: 30}, Coordinate{Latitude: -80, Longitude: -150}},
+		{Coordinate{Latitude: 200, Longitude: 30}, Coordinate{Latitude: -20, Longitude: -150}},
+		{Coordinate{Latitude: 450, Longitude: 30}, Coordinate{Latitude: 90, Longitude: 30}},
+	}
+	for _, c := range cases {
+		got := c.in.Normalize()
+		if math.Abs(got.Latitude-c.want.Latitude) > closeEnough || math.Abs(got.Longitude-c.want.Longitude) > closeEnough {
+			t.Errorf("%v normalized to %v, wanted %v", c.in, got, c.want)
+		}
+	}
+
+	// Normalizing never moves a point
+	for i := 0; i < 10000; i++ {
+		c := Coordinate{
+			Latitude:  rand.Float64()*2000 - 1000,
+			Longitude: rand.Float64()*2000 - 1000,
+		}
+		n, err := NewNormalizedCoordinate(c.Latitude, c.Longitude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Validate(); err != nil || n.Longitude >= 180 {
+			t.Errorf("%v normalized to %v, which is out of range", c, n)
+		}
+		a, b := toVector(c), toVector(n)
+		if d := math.Abs(a[0]-b[0]) + math.Abs(a[1]-b[1]) + math.Abs(a[2]-b[2]); d > closeEnough {
+			t.Errorf("%v normalized to %v, which is elsewhere", c, n)
+		}
+	}
+}
+
+// Check that out of range and non-numeric coordinates are rejected by
+// the constructors and when unmarshaling.
+func TestValidate(t *testing.T) {
+	bad := []struct {
+		latitude, longitude float64
+		field               string
+	}{
+		{200, 0, "Latitude"},
+		{-90.5, 0, "Latitude"},
+		{math.NaN(), 0, "Latitude"},
+		{0, 180.5, "Longitude"},
+		{0, math.Inf(-1), "Longitude"},
+	}
+	for _, c := range bad {
+		_, err := NewCoordinate(c.latitude, c.longitude)
+		if e, ok := err.(*RangeError); !ok || e.Field != c.field {
+			t.Errorf("NewCoordinate(%f, %f) returned %v, wanted a bad %s", c.latitude, c.longitude, err, c.field)
+		}
+	}
+	if _, err := NewNormalizedCoordinate(math.NaN(), 0); err == nil {
+		t.Errorf("NewNormalizedCoordinate(NaN, 0) succeeded")
+	}
+	if c, err := NewCoordinate(-90, 180); err != nil || c != (Coordinate{Latitude: -90, Longitude: 180}) {
+		t.Errorf("NewCoordinate(-90, 180) returned %v, %v", c, err)
+	}
+
+	var c Coordinate
+	if err := json.Unmarshal([]byte(`{"Latitude": 200, "Longitude": 0}`), &c); err == nil {
+		t.Errorf("Unmarshaling latitude 200 succeeded")
+	} else if err.Error() != "Latitude 200 is out of range [-90, 90]" {
+		t.Errorf("Unmarshaling latitude 200 failed with '%s'", err)
+	}
+	if _, err := json.Marshal(Coordinate{Latitude: 0, Longitude: 181}); err == nil {
+		t.Errorf("Marshaling longitude 181 succeeded")
+	}
+}
+
+// Check that altitudes are read from Altituders and counted by
+// WithAltitude, and that they survive normalization.
+func TestAltitude(t *testing.T) {
+	a := Coordinate{Latitude: 0, Longitude: 0}
+	b := Coordinate{Latitude: 0, Longitude: 1, Altitude: Kilometers(10)}
+	if got := Altitude(b); got != Kilometers(10) {
+		t.Errorf("Altitude(%v) was %f", b, got)
+	}
+	if got := Altitude(&b); got != Kilometers(10) {
+		t.Errorf("Altitude(&%v) was %f", b, got)
+	}
+
+	surface := Distance(a, b)
+	want := math.Hypot(surface.Meters(), 10000)
+	if got := WithAltitude(Distance)(a, b).Meters(); math.Abs(got-want) > closeEnough {
+		t.Errorf("3D distance was %f m, wanted %f m", got, want)
+	}
+	if got := WithAltitude(Distance)(b, a).Meters(); math.Abs(got-want) > closeEnough {
+		t.Errorf("3D distance descending was %f m, wanted %f m", got, want)
+	}
+	if got := WithAltitude(Distance)(b, b); got != 0 {
+		t.Errorf("3D distance from a point to itself was %f", got)
+	}
+
+	if n := (Coordinate{Latitude: 100, Longitude: 0, Altitude: Meters(5)}).Normalize(); n.Altitude != Meters(5) {
+		t.Errorf("Normalizing lost the altitude: %v", n)
+	}
+
+	var c Coordinate
+	if err := json.Unmarshal([]byte(`{"Latitude": 1, "Longitude": 2, "Altitude": 300}`), &c); err != nil || c.Altitude != Meters(300) {
+		t.Errorf("Unmarshaling an altitude gave %v, %v", c, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Latitude": 1, "Longitude": 2, "Altitude": "high"}`), &c); err == nil {
+		t.Errorf("Unmarshaling a string altitude succeeded")
+	}
+	if err := json.Unmarshal([]byte(`{"Latitude": 1, "Longitude": 2, "Height": 300}`), &c); err == nil {
+		t.Errorf("Unmarshaling an unknown field succeeded")
+	}
+}
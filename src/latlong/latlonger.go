@@ -4,24 +4,74 @@ import (
 	"math"
 )
 
+// Radius of the spherical earth used by Distance and Destination
+const earthRadius = 3958.76 * Mile
+
 // A LatLonger can return its position on earth in terms of latitude and longitude
 type LatLonger interface {
 	Lat() float64
 	Lon() float64
 }
 
+// An Altituder is a LatLonger that can also return its height above
+// the reference ellipsoid. Positions that are not Altituders lie on the
+// ellipsoid.
+type Altituder interface {
+	LatLonger
+	Alt() Length
+}
+
+// Altitude of l above the reference ellipsoid, zero if l is not an
+// Altituder
+func Altitude(l LatLonger) Length {
+	if a, ok := l.(Altituder); ok {
+		return a.Alt()
+	}
+	return 0
+}
+
+// WithAltitude turns a function measuring distance over the surface
+// into one that also counts the climb or descent between a and b. The
+// leg is taken as the hypotenuse of the surface distance and the
+// change in altitude.
+func WithAltitude(surface func(a, b LatLonger) Length) func(a, b LatLonger) Length {
+	return func(a, b LatLonger) Length {
+		d, climb := surface(a, b), Altitude(b)-Altitude(a)
+		return Length(math.Hypot(float64(d), float64(climb)))
+	}
+}
+
 // Computes hsin of angle theta in radians
 func hsin(theta float64) float64 {
 	return math.Pow(math.Sin(theta/2), 2)
 }
 
-// Distance (in miles) between two LatLongers: a and b
-func Distance(a, b LatLonger) float64 {
+// Distance between two LatLongers: a and b, on a spherical earth
+func Distance(a, b LatLonger) Length {
 	latA, lonA := rad(a.Lat()), rad(a.Lon())
 	latB, lonB := rad(b.Lat()), rad(b.Lon())
 
-	r := 3958.76 // Earth's radius in miles
 	h := hsin(latB-latA) + math.Cos(latA)*math.Cos(latB)*hsin(lonB-lonA)
 
-	return 2 * r * math.Asin(math.Sqrt(h))
+	return earthRadius * Length(2*math.Asin(math.Sqrt(h)))
+}
+
+// Destination reached by flying a great circle from start, leaving on
+// the given bearing (degrees clockwise from true north) for distance
+func Destination(start LatLonger, bearing float64, distance Length) Coordinate {
+	lat1, lon1 := rad(start.Lat()), rad(start.Lon())
+	theta := rad(bearing)
+	delta := float64(distance / earthRadius) // Angular distance
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	lon2 := lon1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1),
+		math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	return Coordinate{Latitude: deg(lat2), Longitude: angNormalize(deg(lon2))}
+}
+
+// GeodesicDistance between two LatLongers: a and b, measured along
+// the shortest path on the WGS84 ellipsoid
+func GeodesicDistance(a, b LatLonger) Length {
+	return WGS84.Distance(a, b)
 }
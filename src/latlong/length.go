@@ -0,0 +1,78 @@
+package latlong
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Length is a distance on or above the earth, stored in meters
+type Length float64
+
+// Common units of length
+const (
+	Meter        Length = 1
+	Kilometer           = 1000 * Meter
+	Foot                = 0.3048 * Meter
+	Mile                = 1609.344 * Meter // Statute mile
+	NauticalMile        = 1852 * Meter
+)
+
+// Meters creates a Length of m meters
+func Meters(m float64) Length { return Length(m) * Meter }
+
+// Kilometers creates a Length of km kilometers
+func Kilometers(km float64) Length { return Length(km) * Kilometer }
+
+// Feet creates a Length of ft feet
+func Feet(ft float64) Length { return Length(ft) * Foot }
+
+// Miles creates a Length of mi statute miles
+func Miles(mi float64) Length { return Length(mi) * Mile }
+
+// NauticalMiles creates a Length of nmi nautical miles
+func NauticalMiles(nmi float64) Length { return Length(nmi) * NauticalMile }
+
+// Meters returns the length in meters
+func (l Length) Meters() float64 { return float64(l / Meter) }
+
+// Kilometers returns the length in kilometers
+func (l Length) Kilometers() float64 { return float64(l / Kilometer) }
+
+// Feet returns the length in feet
+func (l Length) Feet() float64 { return float64(l / Foot) }
+
+// Miles returns the length in statute miles
+func (l Length) Miles() float64 { return float64(l / Mile) }
+
+// NauticalMiles returns the length in nautical miles
+func (l Length) NauticalMiles() float64 { return float64(l / NauticalMile) }
+
+// In returns the length as a number of the given unit
+func (l Length) In(u Unit) float64 { return float64(l / u.Length) }
+
+// Unit is a named unit of length, used to present a Length to people
+type Unit struct {
+	Name   string // Plural name, e.g. "miles"
+	Symbol string // Abbreviation, e.g. "mi"
+	Length Length // Size of one unit
+}
+
+// Units lists the units understood by ParseUnit
+var Units = []Unit{
+	{"meters", "m", Meter},
+	{"kilometers", "km", Kilometer},
+	{"feet", "ft", Foot},
+	{"miles", "mi", Mile},
+	{"nautical miles", "nmi", NauticalMile},
+}
+
+// ParseUnit finds a unit in Units by name or symbol, ignoring case
+func ParseUnit(s string) (Unit, error) {
+	for _, u := range Units {
+		if strings.EqualFold(s, u.Name) || strings.EqualFold(s, u.Symbol) {
+			return u, nil
+		}
+	}
+	return Unit{}, errors.New(fmt.Sprintf("Unknown unit of length '%s'", s))
+}
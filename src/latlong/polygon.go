@@ -0,0 +1,174 @@
+package latlong
+
+import (
+	"math"
+)
+
+// Polygon is a closed region of the earth's surface bounded by edges
+// between consecutive vertices, the last vertex joining back to the
+// first. Edges are great circles on the sphere and geodesics on an
+// ellipsoid.
+//
+// A closed boundary divides the earth in two; the interior of a
+// Polygon is always the smaller of the two regions, whichever way its
+// vertices wind.
+type Polygon []LatLonger
+
+// Area of the spherical earth, in square meters
+var earthArea = 4 * math.Pi * sq(earthRadius.Meters())
+
+// 1 if the edge from lon1 to lon2 crosses the prime meridian heading
+// east, -1 if heading west and 0 otherwise
+func transit(lon1, lon2 float64) int {
+	lon12, _ := angDiff(lon1, lon2)
+	lon1, lon2 = angNormalize(lon1), angNormalize(lon2)
+	switch {
+	case lon12 > 0 && ((lon1 < 0 && lon2 >= 0) || (lon1 > 0 && lon2 == 0)):
+		return 1
+	case lon12 < 0 && lon1 >= 0 && lon2 < 0:
+		return -1
+	}
+	return 0
+}
+
+// Signed area of the region to the left of the boundary, positive
+// when the vertices wind counter-clockwise around it, reduced to
+// (-total/2, total/2]. edge gives the area between an edge and the
+// equator, and total is the area of the whole earth.
+func signedArea(p Polygon, edge func(a, b LatLonger) float64, total float64) float64 {
+	var area float64
+	crossings := 0
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		area -= edge(a, b)
+		crossings += transit(a.Lon(), b.Lon())
+	}
+
+	// Polygons around a pole leave out the cap between the pole and the equator
+	if crossings%2 != 0 {
+		if area < 0 {
+			area += total / 2
+		} else {
+			area -= total / 2
+		}
+	}
+
+	if area > total/2 {
+		area -= total
+	} else if area <= -total/2 {
+		area += total
+	}
+	return area
+}
+
+// Area (in square meters) between the great circle from a to b and
+// the equator on the spherical earth
+func sphericalEdgeArea(a, b LatLonger) float64 {
+	lon12, _ := angDiff(a.Lon(), b.Lon())
+	t1, t2 := math.Tan(rad(a.Lat())/2), math.Tan(rad(b.Lat())/2)
+	excess := 2 * math.Atan2(math.Tan(rad(lon12)/2)*(t1+t2), 1+t1*t2)
+	return excess * sq(earthRadius.Meters())
+}
+
+// Area of the polygon in square meters, on the spherical earth
+func (p Polygon) Area() float64 {
+	if len(p) < 3 {
+		return 0
+	}
+	return math.Abs(signedArea(p, sphericalEdgeArea, earthArea))
+}
+
+// Perimeter of the polygon, on the spherical earth
+func (p Polygon) Perimeter() Length {
+	var perimeter Length
+	for i, a := range p {
+		perimeter += Distance(a, p[(i+1)%len(p)])
+	}
+	return perimeter
+}
+
+// Contains reports whether point lies inside the polygon. Edges are
+// treated as great circles, and may cross the antimeridian or run
+// around a pole.
+func (p Polygon) Contains(point LatLonger) bool {
+	if len(p) < 3 {
+		return false
+	}
+
+	// Cast a ray due north from point to the pole, counting the edges
+	// it crosses. An odd count puts point and the pole on opposite
+	// sides of the boundary.
+	lat, lon := point.Lat(), point.Lon()
+	meridian := [3]float64{-math.Sin(rad(lon)), math.Cos(rad(lon)), 0} // Normal to the meridian plane
+	crossings := 0
+	var sweep float64 // Total longitude swept by the boundary
+	for i, a := range p {
+		b := p[(i+1)%len(p)]
+		lon12, _ := angDiff(a.Lon(), b.Lon())
+		sweep += lon12
+
+		d1, _ := angDiff(lon, a.Lon())
+		d2, _ := angDiff(lon, b.Lon())
+		if (d1 >= 0) == (d2 >= 0) || math.Abs(d2-d1) >= 180 {
+			// Edge stays on one side of the meridian, or crosses the
+			// antimeridian instead
+			continue
+		}
+
+		// Latitude at which the edge's great circle meets the meridian
+		x := cross3(cross3(toVector(a), toVector(b)), meridian)
+		if x[0]*math.Cos(rad(lon))+x[1]*math.Sin(rad(lon)) < 0 {
+			x = [3]float64{-x[0], -x[1], -x[2]}
+		}
+		if deg(math.Atan2(x[2], math.Hypot(x[0], x[1]))) > lat {
+			crossings++
+		}
+	}
+
+	// The north pole is inside when the boundary encircles it and the
+	// polygon's interior lies on the pole's side
+	northInside := false
+	if math.Abs(sweep) > 180 {
+		eastward := sweep > 0 // Counter-clockwise around the north pole
+		northInside = eastward == (signedArea(p, sphericalEdgeArea, earthArea) > 0)
+	}
+
+	return northInside != (crossings%2 == 1)
+}
+
+// Unit vector from the center of the earth through a LatLonger
+func toVector(l LatLonger) [3]float64 {
+	lat, lon := rad(l.Lat()), rad(l.Lon())
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+// PolygonArea is the area of the polygon in square meters, with
+// geodesic edges on the ellipsoid
+func (e *Ellipsoid) PolygonArea(p Polygon) float64 {
+	if len(p) < 3 {
+		return 0
+	}
+	edge := func(a, b LatLonger) float64 {
+		_, _, _, _, _, _, S12 := e.geod.genInverse(a.Lat(), a.Lon(), b.Lat(), b.Lon(), true)
+		return S12
+	}
+	return math.Abs(signedArea(p, edge, 4*math.Pi*e.geod.c2))
+}
+
+// PolygonPerimeter is the perimeter of the polygon, with geodesic
+// edges on the ellipsoid
+func (e *Ellipsoid) PolygonPerimeter(p Polygon) Length {
+	var perimeter Length
+	for i, a := range p {
+		perimeter += e.Distance(a, p[(i+1)%len(p)])
+	}
+	return perimeter
+}
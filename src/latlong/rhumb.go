@@ -0,0 +1,85 @@
+package latlong
+
+import (
+	"math"
+)
+
+// Rhumb lines (loxodromes) cross every meridian at the same angle, so
+// they are flown at a constant compass heading. They are longer than
+// great circles except along the equator and the meridians.
+//
+// Reference for rhumb lines can be found here:
+//     - https://en.wikipedia.org/wiki/Rhumb_line
+
+// Difference in isometric latitude between latitudes latA and latB
+// (radians), the northing of a Mercator projection
+func isometricDiff(latA, latB float64) float64 {
+	return math.Log(math.Tan(math.Pi/4+latB/2) / math.Tan(math.Pi/4+latA/2))
+}
+
+// Ratio of latitude difference to isometric latitude difference,
+// falling back to the cosine of the latitude on east-west lines
+func rhumbStretch(dLat, dPsi, lat float64) float64 {
+	if math.Abs(dPsi) > 1e-12 {
+		return dLat / dPsi
+	}
+	return math.Cos(lat)
+}
+
+// Longitude difference from lonA to lonB (radians), taking the short
+// way around across the antimeridian
+func shortLonDiff(lonA, lonB float64) float64 {
+	return rad(angNormalize(deg(lonB - lonA)))
+}
+
+// RhumbDistance between two LatLongers: a and b, following the line of
+// constant bearing between them on a spherical earth
+func RhumbDistance(a, b LatLonger) Length {
+	latA, latB := rad(a.Lat()), rad(b.Lat())
+	dLat := latB - latA
+	dLon := shortLonDiff(rad(a.Lon()), rad(b.Lon()))
+	q := rhumbStretch(dLat, isometricDiff(latA, latB), latA)
+
+	return earthRadius * Length(math.Hypot(dLat, q*dLon))
+}
+
+// RhumbBearing is the constant bearing flown on the rhumb line from a
+// to b, in degrees clockwise from true north in [0, 360)
+func RhumbBearing(a, b LatLonger) float64 {
+	latA, latB := rad(a.Lat()), rad(b.Lat())
+	dLon := shortLonDiff(rad(a.Lon()), rad(b.Lon()))
+
+	return wrap360(deg(math.Atan2(dLon, isometricDiff(latA, latB))))
+}
+
+// RhumbDestination reached by flying from start at a constant bearing
+// (degrees clockwise from true north) for distance. A rhumb line along
+// a meridian passes over a pole onto the opposite meridian. Any other
+// rhumb line spirals into the pole and ends there, so flying farther
+// stops at the pole, keeping the longitude of start.
+func RhumbDestination(start LatLonger, bearing float64, distance Length) Coordinate {
+	lat1, lon1 := rad(start.Lat()), rad(start.Lon())
+	theta := rad(bearing)
+	delta := float64(distance / earthRadius) // Angular distance
+
+	dLat := delta * math.Cos(theta)
+	lat2 := lat1 + dLat
+	if math.Abs(lat2) > math.Pi/2 {
+		if math.Abs(math.Sin(theta)) < 1e-12 {
+			// Along a meridian, fold over the pole
+			return Coordinate{Latitude: deg(lat2), Longitude: deg(lon1)}.Normalize()
+		}
+		return Coordinate{Latitude: math.Copysign(90, lat2), Longitude: deg(lon1)}
+	}
+
+	q := rhumbStretch(dLat, isometricDiff(lat1, lat2), lat1)
+	lon2 := lon1 + delta*math.Sin(theta)/q
+
+	return Coordinate{Latitude: deg(lat2), Longitude: angNormalize(deg(lon2))}
+}
+
+// RhumbInterpolate finds the point a fraction f of the way along the
+// rhumb line from a to b, where f = 0 gives a and f = 1 gives b
+func RhumbInterpolate(a, b LatLonger, f float64) Coordinate {
+	return RhumbDestination(a, RhumbBearing(a, b), RhumbDistance(a, b)*Length(f))
+}
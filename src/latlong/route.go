@@ -0,0 +1,91 @@
+package latlong
+
+import (
+	"math"
+)
+
+// CrossTrackDistance of point from the great circle through start and
+// end. The distance is positive when point lies to the right of the
+// course from start to end, and negative when it lies to the left.
+func CrossTrackDistance(point, start, end LatLonger) Length {
+	d13 := float64(Distance(start, point) / earthRadius)
+	t13 := rad(InitialBearing(start, point))
+	t12 := rad(InitialBearing(start, end))
+
+	return earthRadius * Length(math.Asin(math.Sin(d13)*math.Sin(t13-t12)))
+}
+
+// AlongTrackDistance from start to the point on the great circle
+// through start and end that is closest to point. The distance is
+// negative when that point lies behind start.
+func AlongTrackDistance(point, start, end LatLonger) Length {
+	d13 := float64(Distance(start, point) / earthRadius)
+	t13 := rad(InitialBearing(start, point))
+	t12 := rad(InitialBearing(start, end))
+	dxt := math.Asin(math.Sin(d13) * math.Sin(t13-t12))
+
+	dat := math.Acos(math.Max(-1, math.Min(1, math.Cos(d13)/math.Cos(dxt))))
+	if math.Cos(t13-t12) < 0 {
+		dat = -dat
+	}
+	return earthRadius * Length(dat)
+}
+
+// Route is a planned path flown as great circle legs between
+// consecutive waypoints
+type Route []LatLonger
+
+// Deviation describes where a point lies relative to a Route
+type Deviation struct {
+	// Distance from the nearest point of the route, positive to the
+	// right of course and negative to the left
+	CrossTrack Length
+	// Distance along the route to its point nearest to the deviating point
+	AlongTrack Length
+	// Index of the leg of the route nearest to the deviating point
+	Leg int
+}
+
+// Length of the route, following each of its legs in turn
+func (r Route) Length() Length {
+	var total Length
+	for i := 1; i < len(r); i++ {
+		total += Distance(r[i-1], r[i])
+	}
+	return total
+}
+
+// Deviation of point from the nearest leg of the route. Points beyond
+// either end of a leg are measured from that end.
+func (r Route) Deviation(point LatLonger) Deviation {
+	if len(r) == 0 {
+		return Deviation{}
+	}
+	if len(r) == 1 {
+		return Deviation{CrossTrack: Distance(r[0], point)}
+	}
+
+	var best Deviation
+	var legStart Length // Distance along the route to the start of the current leg
+	for i := 1; i < len(r); i++ {
+		start, end := r[i-1], r[i]
+		legLength := Distance(start, end)
+
+		xt := CrossTrackDistance(point, start, end)
+		at := AlongTrackDistance(point, start, end)
+		switch {
+		case legLength == 0 || at < 0:
+			at = 0
+			xt = Length(math.Copysign(float64(Distance(start, point)), float64(xt)))
+		case at > legLength:
+			at = legLength
+			xt = Length(math.Copysign(float64(Distance(end, point)), float64(xt)))
+		}
+
+		if i == 1 || math.Abs(float64(xt)) < math.Abs(float64(best.CrossTrack)) {
+			best = Deviation{CrossTrack: xt, AlongTrack: legStart + at, Leg: i - 1}
+		}
+		legStart += legLength
+	}
+	return best
+}
@@ -0,0 +1,189 @@
+// Package maidenhead converts between positions on earth and Maidenhead
+// locators, the grid squares amateur radio operators use to report
+// their position, such as FN31pr
+//
+// A locator is made of up to five pairs of characters, each pair
+// naming a smaller cell within the last: a field of 20° of longitude
+// by 10° of latitude (letters A to R), a square of 2° by 1° (digits),
+// a subsquare of 5' by 2.5' (letters a to x), an extended square of
+// 30" by 15" (digits) and an extended subsquare of 1.25" by 0.625"
+// (letters a to x). In each pair the first character is longitude and
+// the second latitude.
+//
+// Reference for Maidenhead locators can be found here: https://en.wikipedia.org/wiki/Maidenhead_Locator_System
+package maidenhead
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+)
+
+// MaxLength is the longest locator Encode will produce and Decode will
+// accept
+const MaxLength = 10
+
+// Number of divisions of each pair of a locator, and the character of
+// the first
+var pairs = []struct {
+	divisions int
+	first     byte
+}{
+	{18, 'A'},
+	{10, '0'},
+	{24, 'a'},
+	{10, '0'},
+	{24, 'a'},
+}
+
+// Encode finds the locator of point with the given number of
+// characters, which is rounded down to an even number and clamped to
+// [2, MaxLength]. Points whose latitude or longitude is out of range or
+// not a number are rejected with a *latlong.RangeError.
+func Encode(point latlong.LatLonger, length int) (string, error) {
+	if length > MaxLength {
+		length = MaxLength
+	}
+	if length < 2 {
+		length = 2
+	}
+
+	if err := (latlong.Coordinate{Latitude: point.Lat(), Longitude: point.Lon()}).Validate(); err != nil {
+		return "", err
+	}
+	lon := math.Mod(point.Lon()+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	lat := point.Lat() + 90
+	lonSize, latSize := 360.0, 180.0
+
+	locator := make([]byte, 0, length)
+	for _, pair := range pairs[:length/2] {
+		lonSize /= float64(pair.divisions)
+		latSize /= float64(pair.divisions)
+		x, y := split(&lon, lonSize, pair.divisions), split(&lat, latSize, pair.divisions)
+		locator = append(locator, pair.first+byte(x), pair.first+byte(y))
+	}
+	return string(locator), nil
+}
+
+// Find which of the divisions of the given size v falls in, and leave
+// v as the remainder. The top edge belongs to the last division, so
+// that the north pole is in a locator.
+func split(v *float64, size float64, divisions int) int {
+	i := int(math.Floor(*v / size))
+	if i >= divisions {
+		i = divisions - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	*v -= float64(i) * size
+	return i
+}
+
+// Cell is the region of the earth named by a locator
+type Cell struct {
+	Locator        string // In its usual case, e.g. FN31pr
+	Center         latlong.Coordinate
+	LatitudeError  float64 // Half the height of the cell, in degrees
+	LongitudeError float64 // Half the width of the cell, in degrees
+}
+
+// Bounds of the cell
+func (c Cell) Bounds() latlong.BoundingBox {
+	return latlong.BoundingBox{
+		South: c.Center.Latitude - c.LatitudeError,
+		North: c.Center.Latitude + c.LatitudeError,
+		West:  c.Center.Longitude - c.LongitudeError,
+		East:  c.Center.Longitude + c.LongitudeError,
+	}
+}
+
+// Decode finds the cell a locator names. Upper and lower case are both
+// accepted.
+func Decode(locator string) (Cell, error) {
+	if len(locator) < 2 || len(locator) > MaxLength || len(locator)%2 != 0 {
+		return Cell{}, errors.New(fmt.Sprintf("Maidenhead locator '%s' must have 2, 4, 6, 8 or 10 characters", locator))
+	}
+
+	canonical := []byte(locator)
+	lon, lat := -180.0, -90.0
+	lonSize, latSize := 360.0, 180.0
+	for i := 0; i < len(canonical); i += 2 {
+		pair := pairs[i/2]
+		lonSize /= float64(pair.divisions)
+		latSize /= float64(pair.divisions)
+		for j, size := range []float64{lonSize, latSize} {
+			canonical[i+j] = sameCase(canonical[i+j], pair.first)
+			d := int(canonical[i+j]) - int(pair.first)
+			if !(0 <= d && d < pair.divisions) {
+				return Cell{}, errors.New(fmt.Sprintf("Invalid character '%c' in Maidenhead locator '%s'", locator[i+j], locator))
+			}
+			if j == 0 {
+				lon += float64(d) * size
+			} else {
+				lat += float64(d) * size
+			}
+		}
+	}
+
+	return Cell{
+		Locator: string(canonical),
+		Center: latlong.Coordinate{
+			Latitude:  lat + latSize/2,
+			Longitude: lon + lonSize/2,
+		},
+		LatitudeError:  latSize / 2,
+		LongitudeError: lonSize / 2,
+	}, nil
+}
+
+// Convert letter c to the case of letter like
+func sameCase(c, like byte) byte {
+	switch {
+	case 'a' <= like && like <= 'z' && 'A' <= c && c <= 'Z':
+		return c + ('a' - 'A')
+	case 'A' <= like && like <= 'Z' && 'a' <= c && c <= 'z':
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+// UnmarshalJSON decodes a JSON string holding a locator
+func (c *Cell) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("Maidenhead locator must be a JSON string")
+	}
+	decoded, err := Decode(s)
+	if err != nil {
+		return err
+	}
+	*c = decoded
+	return nil
+}
+
+// MarshalJSON encodes the cell as a JSON string holding its locator
+func (c Cell) MarshalJSON() ([]byte, error) {
+	if _, err := Decode(c.Locator); err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.Locator)
+}
+
+// String gives the locator of the cell
+func (c Cell) String() string {
+	return c.Locator
+}
+
+func (c Cell) Lat() float64 {
+	return c.Center.Latitude
+}
+
+func (c Cell) Lon() float64 {
+	return c.Center.Longitude
+}
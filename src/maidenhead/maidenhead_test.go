@@ -0,0 +1,114 @@
+package maidenhead
+
+import (
+	"encoding/json"
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between floating point values
+)
+
+// Known locators, from the Wikipedia article and the ARRL.
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		point   latlong.Coordinate
+		locator string
+	}{
+		{latlong.Coordinate{Latitude: 41.714775, Longitude: -72.727260}, "FN31pr"}, // ARRL headquarters
+		{latlong.Coordinate{Latitude: 48.14666, Longitude: 11.60833}, "JN58td"},    // Munich
+		{latlong.Coordinate{Latitude: -34.91, Longitude: -56.21166}, "GF15vc"},     // Montevideo
+		{latlong.Coordinate{Latitude: 0, Longitude: 0}, "JJ00aa00aa"},
+		{latlong.Coordinate{Latitude: -90, Longitude: -180}, "AA"},
+		{latlong.Coordinate{Latitude: 90, Longitude: 180}, "AR09ax"},
+	}
+	for _, c := range cases {
+		if got, err := Encode(c.point, len(c.locator)); err != nil || got != c.locator {
+			t.Errorf("Encode(%v, %d) was %s, %v, wanted %s", c.point, len(c.locator), got, err, c.locator)
+		}
+	}
+	if got, _ := Encode(cases[0].point, 7); got != "FN31pr" {
+		t.Errorf("Encode with 7 characters gave %s", got)
+	}
+	if got, _ := Encode(cases[0].point, 20); len(got) != MaxLength {
+		t.Errorf("Encode with 20 characters gave %s", got)
+	}
+
+	bad := []latlong.Coordinate{
+		{Latitude: 90.5, Longitude: 0},
+		{Latitude: 0, Longitude: -180.5},
+		{Latitude: math.NaN(), Longitude: 0},
+		{Latitude: 0, Longitude: math.Inf(1)},
+	}
+	for _, point := range bad {
+		if got, err := Encode(point, 6); err == nil {
+			t.Errorf("Encode(%v, 6) was %s, wanted an error", point, got)
+		}
+	}
+}
+
+// Generate 100,000 random lat/long coordinates, encode them at random
+// lengths, decode them, and assert that the cell holds the original
+// point and encodes back to the same locator.
+func TestRandDecode(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		point := latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+		}
+		locator, err := Encode(point, 2+2*rand.Intn(MaxLength/2))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cell, err := Decode(locator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cell.Bounds().Contains(point) {
+			t.Errorf("Cell %+v of %s does not hold %v", cell, locator, point)
+		}
+		if got, _ := Encode(cell, len(locator)); got != locator || cell.Locator != locator {
+			t.Errorf("Center of %s encodes as %s", cell, got)
+		}
+	}
+}
+
+// Check that case is ignored and bad locators are rejected.
+func TestDecode(t *testing.T) {
+	cell, err := Decode("fn31PR")
+	if err != nil || cell.Locator != "FN31pr" {
+		t.Fatalf("Decoding fn31PR gave %+v, %v", cell, err)
+	}
+	if math.Abs(cell.Center.Latitude-41.72916666) > closeEnough || math.Abs(cell.Center.Longitude+72.70833333) > closeEnough {
+		t.Errorf("Center of FN31pr is %v", cell.Center)
+	}
+	if math.Abs(cell.LatitudeError-1.0/48) > closeEnough || math.Abs(cell.LongitudeError-1.0/24) > closeEnough {
+		t.Errorf("FN31pr is %f° by %f°", 2*cell.LatitudeError, 2*cell.LongitudeError)
+	}
+
+	bad := []string{"", "F", "FN3", "FN31pr00aa00", "SN31", "FNa1", "FN31py", "FN31prA0", "FN31pr00y1", "FN31pr00ay", "FÑ31"}
+	for _, s := range bad {
+		if got, err := Decode(s); err == nil {
+			t.Errorf("Decode(%s) was %+v, wanted an error", s, got)
+		}
+	}
+}
+
+// Check that cells marshal and unmarshal as JSON strings.
+func TestMarshal(t *testing.T) {
+	var c Cell
+	if err := json.Unmarshal([]byte(`"fn31pr"`), &c); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(c)
+	if err != nil || string(b) != `"FN31pr"` {
+		t.Errorf("%+v marshaled as %s, %v", c, b, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Latitude": 1, "Longitude": 2}`), &c); err == nil {
+		t.Errorf("Unmarshaling an object as a locator succeeded")
+	}
+}
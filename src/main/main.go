@@ -2,23 +2,130 @@ package main
 
 import (
 	"bufio"
+	"datum"
+	"ecef"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"geofence"
 	"latlong"
 	"log"
+	"maidenhead"
+	"mgrs"
 	"nvector"
 	"os"
-	"utm"
+	"pluscode"
+	"projection"
+	"strconv"
+	"strings"
+	"unicode"
+	"ups"
+	_ "utm" // Registers the UTM zones with package projection
 )
 
 var (
 	// True if we want to see debug output, otherwise false.
 	// Set by the user with the -debug flag
 	debug bool
+
+	// Name of the formula used to measure each leg of a trip.
+	// Set by the user with the -method flag
+	method string
+
+	// Reference ellipsoid used by the geodesic method.
+	// Set by the user with the -ellipsoid flag
+	ellipsoid *latlong.Ellipsoid
+
+	// Unit that distances are reported in.
+	// Set by the user with the -units flag
+	units latlong.Unit
+
+	// True if we want every leg of each trip reported, otherwise false.
+	// Set by the user with the -detail flag
+	detail bool
+
+	// True if legs include the climb or descent between fixes, otherwise
+	// only the distance over the surface counts.
+	// Set by the user with the -altitude flag
+	altitude bool
+
+	// Approved route of each traveler, by traveler ID. Progress along
+	// it and deviation from it are measured on the sphere, whatever the
+	// -method.
+	// Loaded from the file given with the -route flag
+	routes map[int]latlong.Route
+
+	// Farthest a traveler may stray from their approved route, or 0 to
+	// only report how far each traveler strayed.
+	// Set by the user with the -max-deviation flag, in -units
+	maxDeviation latlong.Length
+
+	// Travelers flying at a constant compass heading, whose legs are
+	// always measured as rhumb lines.
+	// Set by the user with the -rhumb-travelers flag
+	rhumbTravelers = travelerSet{}
+
+	// Fences every trip is checked against.
+	// Loaded from the file given with the -geofences flag
+	fences []geofence.Fence
 )
 
+// travelerSet is a set of traveler IDs, given on the command line as a
+// comma separated list
+type travelerSet map[int]bool
+
+func (s travelerSet) String() string {
+	ids := make([]string, 0, len(s))
+	for id := range s {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return strings.Join(ids, ",")
+}
+
+func (s travelerSet) Set(value string) error {
+	for _, field := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return errors.New("Bad traveler ID: " + field)
+		}
+		s[id] = true
+	}
+	return nil
+}
+
+// distanceFuncs maps the names accepted by the -method flag to the
+// function used to measure a single leg of a trip
+var distanceFuncs = map[string]func(a, b latlong.LatLonger) latlong.Length{
+	"haversine": latlong.Distance,
+	"geodesic": func(a, b latlong.LatLonger) latlong.Length {
+		return ellipsoid.Distance(a, b)
+	},
+	"rhumb": latlong.RhumbDistance,
+}
+
+// bearingFuncs maps the names accepted by the -method flag to the
+// functions giving the initial and final headings flown on a leg
+var bearingFuncs = map[string][2]func(a, b latlong.LatLonger) float64{
+	"haversine": {latlong.InitialBearing, latlong.FinalBearing},
+	"geodesic": {
+		func(a, b latlong.LatLonger) float64 { return ellipsoid.InitialBearing(a, b) },
+		func(a, b latlong.LatLonger) float64 { return ellipsoid.FinalBearing(a, b) },
+	},
+	"rhumb": {latlong.RhumbBearing, latlong.RhumbBearing},
+}
+
+// interpolateFuncs maps the names accepted by the -method flag to the
+// function finding points along the path a leg is measured on, which
+// geofences are checked against
+var interpolateFuncs = map[string]func(a, b latlong.LatLonger, f float64) latlong.Coordinate{
+	"haversine": nvector.Interpolate,
+	"geodesic": func(a, b latlong.LatLonger, f float64) latlong.Coordinate {
+		return ellipsoid.Interpolate(a, b, f)
+	},
+	"rhumb": latlong.RhumbInterpolate,
+}
+
 // parseCLIArgs parses options from the command line.
 //
 // Returns the name of the user-provided data file
@@ -29,9 +136,49 @@ func parseCLIArgs() string {
 	}
 
 	flag.BoolVar(&debug, "debug", false, "enable debug output")
+	flag.BoolVar(&altitude, "altitude", false, "include climb and descent between fixes in leg lengths")
+	flag.StringVar(&method, "method", "haversine",
+		"distance formula: haversine (sphere), geodesic (ellipsoid) or rhumb (constant heading)")
+	ellipsoidName := flag.String("ellipsoid", "WGS84",
+		"reference ellipsoid for the geodesic method: WGS84, GRS80, Clarke1866, International1924 or Airy1830")
+	unitName := flag.String("units", "miles", "unit for reported distances: meters, kilometers, feet, miles or nautical miles")
+	flag.BoolVar(&detail, "detail", false, "report the distance and headings of every leg and the extent of every trip")
+	routeFile := flag.String("route", "", "file of approved routes, in the same format as the data file; progress and deviation are measured along great circles on the sphere, whatever the -method")
+	deviation := flag.Float64("max-deviation", 0, "flag travelers straying farther than this from their approved route, in -units (0 only reports)")
+	flag.Var(rhumbTravelers, "rhumb-travelers", "comma separated IDs of travelers whose legs are always measured as rhumb lines")
+	fenceFile := flag.String("geofences", "", "JSON or GeoJSON file of fences to report each traveler's visits to")
 
 	flag.Parse()
 
+	if _, ok := distanceFuncs[method]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown distance method %q!\n\n", method)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var err error
+	if ellipsoid, err = latlong.LookupEllipsoid(*ellipsoidName); err != nil {
+		fmt.Fprintf(os.Stderr, "%s!\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if units, err = latlong.ParseUnit(*unitName); err != nil {
+		fmt.Fprintf(os.Stderr, "%s!\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	maxDeviation = latlong.Length(*deviation) * units.Length
+
+	if *routeFile != "" {
+		routes = loadRoutes(*routeFile)
+	}
+	if *fenceFile != "" {
+		if fences, err = geofence.Load(*fenceFile); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
 	if flag.NArg() != 1 {
 		fmt.Fprintf(os.Stderr, "Need a file to process!\n\n")
 		flag.Usage()
@@ -42,21 +189,41 @@ func parseCLIArgs() string {
 }
 
 // unmarshalLatLonger attempts to unmarshal a JSON encoded
-// latlong.LatLonger coordinate.
+// latlong.LatLonger coordinate. It accepts:
 //
-// The coordinate may be a JSON encoded latlong.Coordinate,
-// nvector.Coordinate, or utm.Coordinate.
+//   - a latlong.Coordinate, ecef.Coordinate, nvector.Coordinate or
+//     ups.Coordinate
+//   - a datum.Coordinate, such as {"Latitude": 40.5, "Longitude":
+//     -105.25, "Datum": "NAD27"}, which is converted to WGS84
+//   - a coordinate on a registered projection, such as a utm.Coordinate
+//     or a projection.Coordinate naming its projection (see
+//     projection.Unmarshal)
+//   - a string holding an MGRS grid reference such as "4QFJ12345678"
+//     (see mgrs.Parse)
+//   - a string holding a Maidenhead locator such as "FN31pr" (see
+//     maidenhead.Decode)
+//   - a string holding a plus code such as "87G8Q2PQ+2V" (see
+//     pluscode.Decode), or a short one such as "Q2PQ+2V", which is
+//     recovered near the coordinate near, if it is not nil
+//   - a string holding degrees, minutes and seconds such as
+//     "40°26'46.3\"N 79°58'56\"W" (see latlong.ParseCoordinate)
 //
-// For each of the above coordinate types, unmarshalLatLonger attempts
-// to unmarshal the string. It starts with latlong.Coordinate. If it
-// successfully unmarshals the string as a latlong.Coordinate, it
-// returns it along with a nil error. If it fails, it tries to
-// unmarshal it as a nvector.Coordinate. unmarshalLatLonger tries each
-// type until one succeeds. If it fails to unmarshal the string to
-// **any** of the above coordinate types, it returns a non-nil error.
+// unmarshalLatLonger tries each type in turn and returns the first
+// that unmarshals the string, along with a nil error: latlong.Coordinate,
+// datum.Coordinate, ecef.Coordinate, nvector.Coordinate, the projection
+// types, ups.Coordinate, then the strings, as MGRS, Maidenhead, full
+// and short plus codes, and degrees, minutes and seconds. If it fails
+// to unmarshal the string to **any** of the above coordinate types, it
+// returns a non-nil error.
 //
 // If unmarshaling is successful, the coordinate is returned as a latlong.LatLonger.
-func unmarshalLatLonger(s string) (l latlong.LatLonger, err error) {
+// If the string is a latitude and longitude that is out of range, or a
+// string that cannot be read as a grid reference, a locator, a plus
+// code or degrees, minutes and seconds, the error says why.
+func unmarshalLatLonger(s string, near latlong.LatLonger) (l latlong.LatLonger, err error) {
+	// Why the coordinate was rejected, if it is clear which type it was meant to be
+	var reason error
+
 	// Try to unmarshal a latlong
 	// fmt.Println([]byte(s))
 	c1 := new(latlong.Coordinate)
@@ -64,6 +231,27 @@ func unmarshalLatLonger(s string) (l latlong.LatLonger, err error) {
 		l = c1
 		err = nil
 		return
+	} else if _, ok := e.(*latlong.RangeError); ok {
+		reason = e
+	}
+
+	// Try to unmarshal a latlong tagged with its datum
+	c10 := new(datum.Coordinate)
+	if e := json.Unmarshal([]byte(s), c10); e == nil {
+		l = c10
+		err = nil
+		return
+	} else if reason == nil && strings.Contains(s, `"Datum"`) {
+		reason = e
+	}
+
+	// Try to unmarshal an ecef. n-vectors share its fields, but are far
+	// too short to be taken for positions near the earth, so try it first.
+	c5 := new(ecef.Coordinate)
+	if e := json.Unmarshal([]byte(s), c5); e == nil {
+		l = c5
+		err = nil
+		return
 	}
 
 	// Try to unmarshal an nvector
@@ -74,21 +262,136 @@ func unmarshalLatLonger(s string) (l latlong.LatLonger, err error) {
 		return
 	}
 
-	// Try to unmarshal a utm
-	c3 := new(utm.Coordinate)
-	if e := json.Unmarshal([]byte(s), c3); e == nil {
+	// Try to unmarshal a coordinate on one of the registered
+	// projections, such as a utm
+	if c3, e := projection.Unmarshal([]byte(s)); e == nil {
 		l = c3
 		err = nil
 		return
+	} else if reason == nil && strings.Contains(s, `"Projection"`) {
+		reason = e
+	}
+
+	// Try to unmarshal a ups
+	c7 := new(ups.Coordinate)
+	if e := json.Unmarshal([]byte(s), c7); e == nil {
+		l = c7
+		err = nil
+		return
+	}
+
+	// Try to unmarshal an MGRS grid reference
+	c6 := new(mgrs.Coordinate)
+	e6 := json.Unmarshal([]byte(s), c6)
+	if e6 == nil {
+		l = c6
+		err = nil
+		return
+	}
+
+	// Try to unmarshal a Maidenhead locator
+	c8 := new(maidenhead.Cell)
+	e8 := json.Unmarshal([]byte(s), c8)
+	if e8 == nil {
+		l = c8
+		err = nil
+		return
+	}
+
+	// Try to unmarshal a full plus code
+	c9 := new(pluscode.Area)
+	e9 := json.Unmarshal([]byte(s), c9)
+	if e9 == nil {
+		l = c9
+		err = nil
+		return
+	}
+
+	var text string
+	if e := json.Unmarshal([]byte(s), &text); e == nil && pluscode.IsShort(text) {
+		// Try to recover a short plus code near the last position
+		if near == nil {
+			reason = errors.New("A short plus code needs an earlier position in the trip to recover it near")
+		} else if full, e := pluscode.RecoverNearest(text, near); e != nil {
+			reason = e
+		} else {
+			c9 := new(pluscode.Area)
+			if *c9, e = pluscode.Decode(full); e == nil {
+				l = c9
+				err = nil
+				return
+			}
+			reason = e
+		}
+	} else if e == nil {
+		// Try to parse a string of degrees, minutes and seconds
+		c4 := new(latlong.Coordinate)
+		if *c4, e = latlong.ParseCoordinate(text); e == nil {
+			l = c4
+			err = nil
+			return
+		} else if _, ok := e.(*latlong.RangeError); ok {
+			reason = e
+		} else if looksLikePlusCode(text) {
+			reason = e9
+		} else if looksLikeLocator(text) {
+			reason = e8
+		} else if looksLikeGridReference(text) {
+			reason = e6
+		} else {
+			reason = e
+		}
 	}
 
 	// Unmarshaling unsuccesful
 	l = nil
 	msg := "Cannot unmarshal coordinate: " + s
+	if reason != nil {
+		msg += " (" + reason.Error() + ")"
+	}
 	err = errors.New(msg)
 	return
 }
 
+// looksLikeLocator reports whether s starts the way a Maidenhead
+// locator does, with two letters and then a digit
+func looksLikeLocator(s string) bool {
+	s = strings.TrimSpace(s)
+	return isLetter(s, 0) && isLetter(s, 1) && (len(s) == 2 || isDigit(s, 2))
+}
+
+// looksLikeGridReference reports whether s starts the way an MGRS grid
+// reference does, with a zone number and a letter, or with the letter
+// of a polar zone and another letter
+func looksLikeGridReference(s string) bool {
+	s = strings.TrimSpace(s)
+	if isDigit(s, 0) {
+		i := 1
+		if isDigit(s, i) {
+			i++
+		}
+		return isLetter(s, i)
+	}
+	return len(s) > 1 && strings.ContainsAny(strings.ToUpper(s[:1]), "ABYZ") && isLetter(s, 1)
+}
+
+// looksLikePlusCode reports whether s has the plus sign of a plus code
+// just after a digit of one
+func looksLikePlusCode(s string) bool {
+	i := strings.IndexRune(s, '+')
+	return i > 0 && (isLetter(s, i-1) || isDigit(s, i-1))
+}
+
+// isLetter reports whether s has an ASCII letter at byte i
+func isLetter(s string, i int) bool {
+	return i < len(s) && ('A' <= s[i] && s[i] <= 'Z' || 'a' <= s[i] && s[i] <= 'z')
+}
+
+// isDigit reports whether s has a digit at byte i
+func isDigit(s string, i int) bool {
+	return i < len(s) && '0' <= s[i] && s[i] <= '9'
+}
+
 // loadTrips loads trip information line-by-line from a file and sends
 // results over a channel.
 //
@@ -108,7 +411,7 @@ func loadTrips(fname string, trips chan trip) {
 	file, err := os.Open(fname)
 	if err != nil {
 		// Error opening the file, presumably does not exist
-		fmt.Println("open %s: no such file or directory", fname)
+		fmt.Printf("open %s: no such file or directory\n", fname)
 		os.Exit(1)
 	}
 	defer file.Close()
@@ -122,11 +425,20 @@ func loadTrips(fname string, trips chan trip) {
 	var tmpID int
 	var tmpJSON string
 	var myCoord latlong.LatLonger
-	tmpCoords := make([]latlong.LatLonger, 1)
+	var tmpCoords []latlong.LatLonger
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		_, err := fmt.Sscanf(scanner.Text(), "%d\t%s", &tmpID, &tmpJSON)
 		if err == nil {
+			// %s stops at the first space, but coordinates may contain
+			// spaces, so take everything after the ID
+			line := strings.TrimSpace(scanner.Text())
+			space := strings.IndexFunc(line, unicode.IsSpace)
+			if space < 0 {
+				fmt.Printf("Malformed line: %s\n", line)
+				os.Exit(1)
+			}
+			tmpJSON = strings.TrimSpace(line[space:])
 			if tmpID != currentID {
 				// Done collecting coordinates for the current trip
 				// Send what we have thru channel, and reset our variables
@@ -134,7 +446,13 @@ func loadTrips(fname string, trips chan trip) {
 				currentID = tmpID
 				tmpCoords = nil
 			}
-			myCoord, err = unmarshalLatLonger(tmpJSON)
+			// Short plus codes are recovered near the previous
+			// position of the trip
+			var near latlong.LatLonger
+			if len(tmpCoords) > 0 {
+				near = tmpCoords[len(tmpCoords)-1]
+			}
+			myCoord, err = unmarshalLatLonger(tmpJSON, near)
 			if err == nil {
 				tmpCoords = append(tmpCoords, myCoord)
 			} else {
@@ -152,6 +470,24 @@ func loadTrips(fname string, trips chan trip) {
 	return
 }
 
+// loadRoutes loads the approved route of each traveler from a file in
+// the same format as the data file, as read by loadTrips.
+func loadRoutes(fname string) map[int]latlong.Route {
+	trips := make(chan trip)
+	go loadTrips(fname, trips)
+
+	routes := make(map[int]latlong.Route)
+	for t := range trips {
+		// loadTrips sends traveler 0 with no coordinates when the file
+		// starts with another traveler
+		if len(t.trajectory) == 0 {
+			continue
+		}
+		routes[t.id] = latlong.Route(t.trajectory)
+	}
+	return routes
+}
+
 // computeDistances continually receives trips over a channel and
 // computes the total travel distance for each trip, sending the
 // totalled results over a channel.
@@ -159,23 +495,86 @@ func loadTrips(fname string, trips chan trip) {
 // After the distance of the last trip has been calculated and sent
 // over the output channel (totals), computeDistances closes the
 // channel to indicate that there will be no more results.
+//
+// Each leg is measured with the formula selected by the -method flag.
+// Travelers listed with the -rhumb-travelers flag are measured as
+// rhumb lines instead. If the -altitude flag is set, legs also count
+// the change in altitude between their ends.
+//
+// If the -detail flag is set, the length and headings of every leg and
+// the trip's bounding box are kept with the total. If the traveler has
+// an approved route, the total records how far the trip strayed from
+// it and how far along it the trip got, and if fences were given, the
+// trip's geofence report.
 func computeDistances(trips chan trip, totals chan total) {
-	var currentDist float64 = 0
+	var currentDist latlong.Length = 0
 	var pPrev, pNext latlong.LatLonger
+	var legs []leg
 	for trip := range trips {
+		distance, bearing, interpolate := distanceFuncs[method], bearingFuncs[method], interpolateFuncs[method]
+		if rhumbTravelers[trip.id] {
+			distance, bearing, interpolate = distanceFuncs["rhumb"], bearingFuncs["rhumb"], interpolateFuncs["rhumb"]
+		}
+		if altitude {
+			distance = latlong.WithAltitude(distance)
+		}
+		route, hasRoute := routes[trip.id]
+		var deviation, progress latlong.Length
 		for _, point := range trip.trajectory {
+			if hasRoute {
+				dev := route.Deviation(point)
+				if d := dev.CrossTrack; d > deviation {
+					deviation = d
+				} else if -d > deviation {
+					deviation = -d
+				}
+				progress = dev.AlongTrack
+			}
+
 			pNext = point
 			if pPrev == nil {
 				// Can't find the distance with just one coordinate!
 				pPrev = point
 				continue
 			}
-			currentDist = currentDist + latlong.Distance(pPrev, pNext)
+			d := distance(pPrev, pNext)
+			currentDist = currentDist + d
+			if detail {
+				legs = append(legs, leg{d, bearing[0](pPrev, pNext), bearing[1](pPrev, pNext)})
+			}
 			pPrev = pNext
 		}
-		totals <- total{trip.id, currentDist}
+		var extent *latlong.BoundingBox
+		if detail {
+			if b, err := latlong.NewBoundingBox(trip.trajectory); err == nil {
+				extent = &b
+			}
+		}
+		var fenceReport *geofence.Report
+		if fences != nil {
+			r := geofence.Evaluate(fences, trip.trajectory, distance, interpolate)
+			fenceReport = &r
+		}
+		var routeReport *routeProgress
+		if hasRoute {
+			routeReport = &routeProgress{
+				length:    route.Length(),
+				progress:  progress,
+				deviation: deviation,
+				strayed:   maxDeviation > 0 && deviation > maxDeviation,
+			}
+		}
+		totals <- total{
+			id:       trip.id,
+			distance: currentDist,
+			legs:     legs,
+			extent:   extent,
+			route:    routeReport,
+			fences:   fenceReport,
+		}
 		pPrev = nil
 		currentDist = 0
+		legs = nil
 	}
 	close(totals)
 }
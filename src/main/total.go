@@ -1,14 +1,97 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"geofence"
+	"latlong"
+	"math"
 )
 
 type total struct {
 	id       int
-	distance float64
+	distance latlong.Length
+	legs     []leg // Only kept for the detailed report
+
+	extent *latlong.BoundingBox // Only kept for the detailed report
+
+	route *routeProgress // Only kept for travelers with an approved route
+
+	fences *geofence.Report // Only kept when fences were given
 }
 
+// routeProgress compares a trip to the traveler's approved route
+type routeProgress struct {
+	length    latlong.Length // Length of the approved route
+	progress  latlong.Length // Distance along the route of the last fix
+	deviation latlong.Length // Farthest distance from the route
+	strayed   bool           // True if deviation is more than allowed
+}
+
+// A leg is the flight between two consecutive coordinates of a trip
+type leg struct {
+	distance latlong.Length
+	initial  float64 // Heading on departure, in degrees from true north
+	final    float64 // Heading on arrival, in degrees from true north
+}
+
+// String reports the total in the unit selected by the -units flag
 func (t total) String() string {
-	return fmt.Sprintf("Traveler %d traveled %.2f miles", t.id, t.distance)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Traveler %d traveled %.2f %s", t.id, t.distance.In(units), units.Name)
+	for i, l := range t.legs {
+		fmt.Fprintf(&buf, "\n    Leg %d: %.2f %s, heading %05.1f° to %05.1f°",
+			i+1, l.distance.In(units), units.Name, heading(l.initial), heading(l.final))
+	}
+	if t.extent != nil {
+		fmt.Fprintf(&buf, "\n    Extent: %.4f° to %.4f° latitude, %.4f° to %.4f° longitude",
+			t.extent.South, t.extent.North, t.extent.West, t.extent.East)
+		if t.extent.CrossesAntimeridian() {
+			buf.WriteString(" (across the antimeridian)")
+		}
+	}
+	if r := t.route; r != nil {
+		fmt.Fprintf(&buf, "\n    Route: %.2f of %.2f %s along, at most %.2f %s off course",
+			r.progress.In(units), r.length.In(units), units.Name, r.deviation.In(units), units.Name)
+		if r.strayed {
+			fmt.Fprintf(&buf, "\nTraveler %d strayed %.2f %s from the approved route",
+				t.id, r.deviation.In(units), units.Name)
+		}
+	}
+	if t.fences != nil {
+		writeFenceReport(&buf, t.id, t.fences)
+	}
+	return buf.String()
+}
+
+// heading rounds a bearing in [0, 360) to the tenth of a degree that
+// reports show, so that bearings just short of north read 000.0
+func heading(bearing float64) float64 {
+	h := math.Floor(bearing*10+0.5) / 10
+	if h >= 360 {
+		h -= 360
+	}
+	return h
+}
+
+// writeFenceReport writes one line per fence visited, followed by
+// every boundary crossing for the detailed report and one line per
+// violation
+func writeFenceReport(buf *bytes.Buffer, id int, r *geofence.Report) {
+	for _, v := range r.Visits {
+		fmt.Fprintf(buf, "\n    Fence %s: entered %d time(s), %.2f %s over %d fix(es) inside",
+			v.Fence.Name, v.Entries, v.Distance.In(units), units.Name, v.Fixes)
+	}
+	if detail {
+		for _, e := range r.Events {
+			action := "Exited"
+			if e.Entered {
+				action = "Entered"
+			}
+			fmt.Fprintf(buf, "\n    %s fence %s at fix %d", action, e.Fence.Name, e.Fix)
+		}
+	}
+	for _, e := range r.Violations {
+		fmt.Fprintf(buf, "\nTraveler %d violated %s fence %s at fix %d", id, e.Fence.Kind, e.Fence.Name, e.Fix)
+	}
 }
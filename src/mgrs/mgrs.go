@@ -0,0 +1,335 @@
+// Package mgrs converts between Military Grid Reference System (MGRS)
+// grid references and UTM or latitude/longitude coordinates on WGS84
+//
+// An MGRS reference such as 4QFJ1234567890 names a UTM zone (4), a
+// latitude band (Q), a 100 km square within the zone (FJ), and a square
+// within that, here 1 m across, by its easting (12345) and northing
+// (67890). Fewer digits name larger squares, down to none at all for
+// the whole 100 km square.
+//
+// Around the poles, where the UPS grid takes over from UTM, references
+// have no zone number. The zone letter is A or B for the west or east
+// of the south pole and Y or Z for the north, as in ZAH0000000000 for
+// the north pole.
+//
+// Reference for MGRS can be found here: https://en.wikipedia.org/wiki/Military_Grid_Reference_System
+package mgrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"utm"
+)
+
+// MaxDigits is the most digits an easting or northing can have,
+// naming a square 1 m across
+const MaxDigits = 5
+
+// Latitude bands, 8° tall from 80°S, the last stretched to 84°N
+const bands = "CDEFGHJKLMNPQRSTUVWX"
+
+// Column letters of 100 km squares, which repeat every three zones
+var columns = [3]string{"STUVWXYZ", "ABCDEFGH", "JKLMNPQR"}
+
+// Row letters of 100 km squares, which repeat every 2,000 km of
+// northing, starting 500 km further north in even zones
+const rows = "ABCDEFGHJKLMNPQRSTUV"
+
+// polarGrid holds the letters of the 100 km squares of a polar zone,
+// and the easting and northing of the first, in units of 100 km
+type polarGrid struct {
+	columns     string
+	firstColumn int
+	rows        string
+	firstRow    int
+}
+
+// Square letters of the polar zones, by zone letter
+var polarGrids = map[string]polarGrid{
+	"A": {"JKLPQRSTUXYZ", 8, "ABCDEFGHJKLMNPQRSTUVWXYZ", 8},
+	"B": {"ABCFGHJKLPQR", 20, "ABCDEFGHJKLMNPQRSTUVWXYZ", 8},
+	"Y": {"RSTUXYZ", 13, "ABCDEFGHJKLMNP", 13},
+	"Z": {"ABCFGHJ", 20, "ABCDEFGHJKLMNP", 13},
+}
+
+// Coordinate is a position given by an MGRS grid reference. It stands
+// for the center of the square the reference names.
+type Coordinate struct {
+	ZoneNumber int    // 0 in the polar zones
+	ZoneLetter string // Latitude band, or polar zone
+	Square     string // 100 km square, e.g. "FJ"
+	Easting    int    // Within the 100 km square, in units of the precision
+	Northing   int    // Within the 100 km square, in units of the precision
+	Digits     int    // Digits in Easting and Northing, from 0 for 100 km to 5 for 1 m
+}
+
+// Size of the square named by the coordinate, in meters
+func (c *Coordinate) precision() float64 {
+	return math.Pow(10, float64(MaxDigits-c.Digits))
+}
+
+// String writes the grid reference without spaces, e.g. 4QFJ1234567890
+func (c Coordinate) String() string {
+	zone := c.ZoneLetter
+	if c.ZoneNumber != 0 {
+		zone = strconv.Itoa(c.ZoneNumber) + zone
+	}
+	if c.Digits == 0 {
+		return zone + c.Square
+	}
+	return fmt.Sprintf("%s%s%0*d%0*d", zone, c.Square, c.Digits, c.Easting, c.Digits, c.Northing)
+}
+
+// Parse reads a grid reference, ignoring case and spaces, e.g.
+// "4QFJ1234567890" or "04Q FJ 12345 67890"
+func Parse(s string) (Coordinate, error) {
+	var c Coordinate
+	ref := strings.ToUpper(strings.Join(strings.Fields(s), ""))
+
+	i := 0
+	for i < len(ref) && i < 2 && unicode.IsDigit(rune(ref[i])) {
+		i++
+	}
+	if len(ref) < i+3 {
+		return c, errors.New(fmt.Sprintf("MGRS reference '%s' needs a zone, a latitude band and a 100 km square", s))
+	}
+	// Polar zones have no zone number, so 0 cannot be written
+	if i > 0 {
+		if c.ZoneNumber, _ = strconv.Atoi(ref[:i]); c.ZoneNumber == 0 {
+			return c, errors.New(fmt.Sprintf("MGRS zone %d is not between 1 and 60", c.ZoneNumber))
+		}
+	}
+	c.ZoneLetter = ref[i : i+1]
+	c.Square = ref[i+1 : i+3]
+	digits := ref[i+3:]
+
+	if len(digits)%2 != 0 || len(digits) > 2*MaxDigits {
+		return c, errors.New(fmt.Sprintf("MGRS reference '%s' needs an easting and a northing of 0 to %d digits each", s, MaxDigits))
+	}
+	c.Digits = len(digits) / 2
+	if c.Digits > 0 {
+		var err error
+		if c.Easting, err = strconv.Atoi(digits[:c.Digits]); err != nil {
+			return c, errors.New(fmt.Sprintf("Bad easting in MGRS reference '%s'", s))
+		}
+		if c.Northing, err = strconv.Atoi(digits[c.Digits:]); err != nil {
+			return c, errors.New(fmt.Sprintf("Bad northing in MGRS reference '%s'", s))
+		}
+	}
+	if err := c.check(); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Check that the fields of the coordinate make up a grid reference
+func (c *Coordinate) check() error {
+	if c.ZoneNumber == 0 {
+		grid, ok := polarGrids[c.ZoneLetter]
+		if !ok {
+			return errors.New(fmt.Sprintf("MGRS polar zone '%s' is not A, B, Y or Z", c.ZoneLetter))
+		}
+		if len(c.Square) != 2 || !strings.Contains(grid.columns, c.Square[:1]) || !strings.Contains(grid.rows, c.Square[1:]) {
+			return errors.New(fmt.Sprintf("'%s' is not a 100 km square of MGRS polar zone %s", c.Square, c.ZoneLetter))
+		}
+		return c.checkDigits()
+	}
+
+	if !(1 <= c.ZoneNumber && c.ZoneNumber <= 60) {
+		return errors.New(fmt.Sprintf("MGRS zone %d is not between 1 and 60", c.ZoneNumber))
+	}
+	if len(c.ZoneLetter) != 1 || !strings.Contains(bands, c.ZoneLetter) {
+		return errors.New(fmt.Sprintf("MGRS latitude band '%s' is not one of %s", c.ZoneLetter, bands))
+	}
+	if len(c.Square) != 2 || !strings.Contains(columns[c.ZoneNumber%3], c.Square[:1]) || !strings.Contains(rows, c.Square[1:]) {
+		return errors.New(fmt.Sprintf("'%s' is not a 100 km square of MGRS zone %d", c.Square, c.ZoneNumber))
+	}
+	return c.checkDigits()
+}
+
+// Check the easting and northing within the 100 km square
+func (c *Coordinate) checkDigits() error {
+	if !(0 <= c.Digits && c.Digits <= MaxDigits) {
+		return errors.New(fmt.Sprintf("MGRS references have 0 to %d digits, not %d", MaxDigits, c.Digits))
+	}
+	limit := int(math.Pow(10, float64(c.Digits)))
+	if !(0 <= c.Easting && c.Easting < limit && 0 <= c.Northing && c.Northing < limit) {
+		return errors.New(fmt.Sprintf("Easting and northing of MGRS reference must have %d digits", c.Digits))
+	}
+	return nil
+}
+
+// FromUTM finds the grid reference of the square, with the given
+// number of digits, that holds a UTM coordinate on WGS84, or a UPS
+// coordinate in zone 0 (see utm.FromUPS)
+func FromUTM(u utm.Coordinate, digits int) (Coordinate, error) {
+	if u.Ellipsoid != nil && u.Ellipsoid != latlong.WGS84 {
+		return Coordinate{}, errors.New(fmt.Sprintf("MGRS references are on WGS84, not %s", u.Ellipsoid))
+	}
+	if !(0 <= digits && digits <= MaxDigits) {
+		return Coordinate{}, errors.New(fmt.Sprintf("MGRS references have 0 to %d digits, not %d", MaxDigits, digits))
+	}
+
+	c := Coordinate{
+		ZoneNumber: u.ZoneNumber,
+		ZoneLetter: strings.ToUpper(u.ZoneLetter),
+		Digits:     digits,
+	}
+	outside := errors.New(fmt.Sprintf("UTM coordinate %v is outside the MGRS grid", u))
+	column := int(math.Floor(u.Easting / 100000))
+	row := int(math.Floor(u.Northing / 100000))
+	if u.ZoneNumber == 0 {
+		grid, ok := polarGrids[c.ZoneLetter]
+		column -= grid.firstColumn
+		row -= grid.firstRow
+		if !ok || !(0 <= column && column < len(grid.columns) && 0 <= row && row < len(grid.rows)) {
+			return Coordinate{}, outside
+		}
+		c.Square = grid.columns[column:column+1] + grid.rows[row:row+1]
+	} else {
+		column--
+		set := columns[u.ZoneNumber%3]
+		if !(0 <= column && column < len(set)) || u.Northing < 0 {
+			return Coordinate{}, outside
+		}
+		row %= len(rows)
+		if u.ZoneNumber%2 == 0 {
+			row = (row + 5) % len(rows)
+		}
+		c.Square = set[column:column+1] + rows[row:row+1]
+	}
+
+	c.Easting = int(math.Floor(math.Mod(u.Easting, 100000) / c.precision()))
+	c.Northing = int(math.Floor(math.Mod(u.Northing, 100000) / c.precision()))
+	if err := c.check(); err != nil {
+		return Coordinate{}, err
+	}
+	return c, nil
+}
+
+// ToCoordinate finds the grid reference of the square, with the given
+// number of digits, that holds a LatLonger
+func ToCoordinate(point latlong.LatLonger, digits int) (Coordinate, error) {
+	u, err := utm.ToCoordinate(point)
+	if err != nil {
+		return Coordinate{}, err
+	}
+	return FromUTM(u, digits)
+}
+
+// ToUTM finds the center of the square named by the grid reference,
+// in zone 0 for the polar zones
+func (c *Coordinate) ToUTM() (utm.Coordinate, error) {
+	if err := c.check(); err != nil {
+		return utm.Coordinate{}, err
+	}
+	if c.ZoneNumber == 0 {
+		grid := polarGrids[c.ZoneLetter]
+		column := grid.firstColumn + strings.Index(grid.columns, c.Square[:1])
+		row := grid.firstRow + strings.Index(grid.rows, c.Square[1:])
+		return utm.Coordinate{
+			Easting:    float64(column)*100000 + (float64(c.Easting)+0.5)*c.precision(),
+			Northing:   float64(row)*100000 + (float64(c.Northing)+0.5)*c.precision(),
+			ZoneLetter: c.ZoneLetter,
+		}, nil
+	}
+
+	column := strings.Index(columns[c.ZoneNumber%3], c.Square[:1])
+	row := strings.Index(rows, c.Square[1:])
+	if c.ZoneNumber%2 == 0 {
+		row = (row + len(rows) - 5) % len(rows)
+	}
+	easting := float64(column+1)*100000 + (float64(c.Easting)+0.5)*c.precision()
+	northing := float64(row)*100000 + (float64(c.Northing)+0.5)*c.precision()
+
+	// Row letters repeat every 2,000 km, more than the height of any
+	// latitude band, so take the repeat nearest the middle of the band
+	band := strings.Index(bands, c.ZoneLetter)
+	middle, err := utm.ToCoordinate(latlong.Coordinate{
+		Latitude:  -76 + 8*float64(band),
+		Longitude: float64(c.ZoneNumber)*6 - 183,
+	})
+	if err != nil {
+		return utm.Coordinate{}, err
+	}
+	northing += 2000000 * math.Floor((middle.Northing-northing)/2000000+0.5)
+
+	return utm.Coordinate{
+		Easting:    easting,
+		Northing:   northing,
+		ZoneNumber: c.ZoneNumber,
+		ZoneLetter: c.ZoneLetter,
+	}, nil
+}
+
+// ToLatLong finds the latitude and longitude of the center of the
+// square named by the grid reference
+func (c *Coordinate) ToLatLong() (latlong.Coordinate, error) {
+	u, err := c.ToUTM()
+	if err != nil {
+		return latlong.Coordinate{}, err
+	}
+	return u.ToLatLong()
+}
+
+// UnmarshalJSON decodes a JSON string holding a grid reference
+func (c *Coordinate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("MGRS reference must be a JSON string")
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// MarshalJSON encodes the grid reference as a JSON string
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.String())
+}
+
+// MarshalText encodes the grid reference as written by String
+func (c Coordinate) MarshalText() ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes any grid reference that Parse accepts
+func (c *Coordinate) UnmarshalText(b []byte) error {
+	parsed, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func (c Coordinate) Lat() float64 {
+	point, err := c.ToLatLong()
+	if err == nil {
+		return point.Latitude
+	}
+	return 0
+}
+
+func (c Coordinate) Lon() float64 {
+	point, err := c.ToLatLong()
+	if err == nil {
+		return point.Longitude
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package mgrs
+
+import (
+	"encoding/json"
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Check known grid references.
+func TestKnownReferences(t *testing.T) {
+	cases := []struct {
+		point  latlong.Coordinate
+		digits int
+		ref    string
+	}{
+		{latlong.Coordinate{Latitude: 38.8895, Longitude: -77.0352}, 4, "18SUJ23480648"}, // Washington Monument
+		{latlong.Coordinate{Latitude: 0, Longitude: 0}, 5, "31NAA6602100000"},
+		{latlong.Coordinate{Latitude: -0.000001, Longitude: 0}, 1, "31MAV69"},
+		{latlong.Coordinate{Latitude: 21.3099, Longitude: -157.9167}, 0, "4QFJ"},
+		{latlong.Coordinate{Latitude: 90, Longitude: 0}, 5, "ZAH0000000000"},
+		{latlong.Coordinate{Latitude: -90, Longitude: 0}, 5, "BAN0000000000"},
+	}
+	for _, c := range cases {
+		got, err := ToCoordinate(c.point, c.digits)
+		if err != nil {
+			t.Errorf("ToCoordinate(%v) failed: %s", c.point, err)
+			continue
+		}
+		if got.String() != c.ref {
+			t.Errorf("ToCoordinate(%v, %d) was %s, wanted %s", c.point, c.digits, got, c.ref)
+		}
+	}
+}
+
+// Generate 100,000 random lat/long coordinates, convert them to grid references of random precision, convert the
+// references back, and assert that they name the square holding the
+// original point.
+func TestRandPoints(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		want := latlong.Coordinate{
+			Latitude:  -90 + rand.Float64()*180,
+			Longitude: -180 + rand.Float64()*360,
+		}
+		digits := rand.Intn(MaxDigits + 1)
+
+		ref, err := ToCoordinate(want, digits)
+		if err != nil {
+			t.Fatalf("ToCoordinate(%v) failed: %s", want, err)
+		}
+		parsed, err := Parse(ref.String())
+		if err != nil || parsed != ref {
+			t.Fatalf("%s parsed as %v, %v", ref, parsed, err)
+		}
+		center, err := ref.ToLatLong()
+		if err != nil {
+			t.Fatalf("%s did not convert back: %s", ref, err)
+		}
+
+		// The center is at most half a square's diagonal from the point,
+		// which UTM stretches by up to 0.1% near the edge of a zone and
+		// UPS shrinks by 0.6% at the poles, give or take the meter that
+		// the utm package's series can be out by
+		limit := latlong.Meters(ref.precision()*math.Sqrt2/2/0.994 + 1)
+		if d := latlong.GeodesicDistance(want, center); d > limit {
+			t.Fatalf("%v is %f m from the center of %s, more than %f m", want, d, ref, limit)
+		}
+		// Squares at the edge of a zone may be centered beyond it, so
+		// stay in the zone to find the square again
+		u, _ := ref.ToUTM()
+		if again, err := FromUTM(u, digits); err != nil || again != ref {
+			t.Fatalf("Center of %s is in %s", ref, again)
+		}
+	}
+}
+
+// Check parsing of spaced and lower case references, and that bad
+// references are rejected.
+func TestParse(t *testing.T) {
+	want := Coordinate{ZoneNumber: 4, ZoneLetter: "Q", Square: "FJ", Easting: 12345, Northing: 67890, Digits: 5}
+	for _, s := range []string{"4QFJ1234567890", "04Q FJ 12345 67890", "4qfj 12345 67890"} {
+		if got, err := Parse(s); err != nil || got != want {
+			t.Errorf("Parse(%s) was %v, %v", s, got, err)
+		}
+	}
+	polar := Coordinate{ZoneLetter: "Z", Square: "AH", Easting: 12, Northing: 34, Digits: 2}
+	if got, err := Parse("Z AH 12 34"); err != nil || got != polar {
+		t.Errorf("Parse(Z AH 12 34) was %v, %v", got, err)
+	}
+
+	bad := []string{
+		"",
+		"QFJ12345678",
+		"4QFJ123456789",
+		"4QFJ123456789012",
+		"61QFJ1234",
+		"4IFJ1234",
+		"4QSJ1234",
+		"4QFW1234",
+		"4QFJ12a4",
+		"4AFJ1234",
+		"ZSH1234",
+		"YAA1234",
+		"ZAQ1234",
+		"0ZAH1234",
+	}
+	for _, s := range bad {
+		if got, err := Parse(s); err == nil {
+			t.Errorf("Parse(%s) was %v, wanted an error", s, got)
+		}
+	}
+}
+
+// Check that references marshal and unmarshal as JSON strings.
+func TestMarshal(t *testing.T) {
+	var c Coordinate
+	if err := json.Unmarshal([]byte(`"4QFJ1234567890"`), &c); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(c)
+	if err != nil || string(b) != `"4QFJ1234567890"` {
+		t.Errorf("%v marshaled as %s, %v", c, b, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Latitude": 1, "Longitude": 2}`), &c); err == nil {
+		t.Errorf("Unmarshaling an object as MGRS succeeded")
+	}
+}
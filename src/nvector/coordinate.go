@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"latlong"
 	"math"
+	"strconv"
+	"strings"
 )
 
 // Convert angle in radians to angle in degrees
@@ -18,16 +20,17 @@ func deg(rad float64) float64 { return rad * 180 / math.Pi }
 func rad(deg float64) float64 { return deg * math.Pi / 180 }
 
 // Coordinate represents a position on earth in the n-vector
-// horizontal position representation
+// horizontal position representation, and optionally by altitude
 type Coordinate struct {
-	X, Y, Z float64
+	X, Y, Z  float64
+	Altitude latlong.Length // Height above the reference ellipsoid
 }
 
 // Convert an n-vector Coordinate to its corresponding LatLon
 func (c *Coordinate) ToLatLong() latlong.Coordinate {
 	lat := deg(math.Atan2(c.Z, math.Hypot(c.X, c.Y)))
 	lon := deg(math.Atan2(c.Y, c.X))
-	return latlong.Coordinate{lat, lon}
+	return latlong.Coordinate{Latitude: lat, Longitude: lon, Altitude: c.Altitude}
 }
 
 // Convert a LatLongto its corresponding n-vector Coordinate
@@ -38,6 +41,8 @@ func ToCoordinate(l latlong.LatLonger) Coordinate {
 		X: deg(math.Cos(rlat) * math.Cos(rlon)),
 		Y: deg(math.Cos(rlat) * math.Sin(rlon)),
 		Z: deg(math.Sin(rlat)),
+
+		Altitude: latlong.Altitude(l),
 	}
 }
 
@@ -47,8 +52,9 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	// Check number of fields in JSON object
-	if len(obj) > 3 {
+	// Check number of fields in JSON object. The Altitude field is optional.
+	_, hasAltitude := obj["Altitude"]
+	if len(obj) > 4 || (len(obj) == 4 && !hasAltitude) {
 		return errors.New(fmt.Sprintf("Too many fields for nvector.Coordinate"))
 	}
 	if len(obj) < 3 {
@@ -79,10 +85,67 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 		return errors.New("Wrong type for field 'Z'")
 	}
 
+	// Check Altitude
+	var altitude float64
+	if hasAltitude {
+		var ok bool
+		if altitude, ok = obj["Altitude"].(float64); !ok {
+			return errors.New("Wrong type for field 'Altitude'")
+		}
+	}
+
 	// All clear
 	c.X = obj["X"].(float64)
 	c.Y = obj["Y"].(float64)
 	c.Z = obj["Z"].(float64)
+	c.Altitude = latlong.Meters(altitude)
+	return nil
+}
+
+// MarshalJSON encodes the coordinate as an object with the three fields
+// UnmarshalJSON requires, and the altitude in meters if it is not zero
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		X, Y, Z  float64
+		Altitude float64 `json:",omitempty"`
+	}{c.X, c.Y, c.Z, c.Altitude.Meters()})
+}
+
+// MarshalText encodes the coordinate as its three components separated
+// by spaces, e.g. "10 20 30", followed by the altitude in meters if it
+// is not zero
+func (c Coordinate) MarshalText() ([]byte, error) {
+	var b []byte
+	components := []float64{c.X, c.Y, c.Z}
+	if c.Altitude != 0 {
+		components = append(components, c.Altitude.Meters())
+	}
+	for i, v := range components {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("Cannot marshal an nvector.Coordinate that is not finite")
+		}
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = strconv.AppendFloat(b, v, 'g', -1, 64)
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes the format written by MarshalText
+func (c *Coordinate) UnmarshalText(b []byte) error {
+	fields := strings.Fields(string(b))
+	if len(fields) != 3 && len(fields) != 4 {
+		return errors.New("nvector.Coordinate needs three components and an optional altitude")
+	}
+	var v [4]float64
+	for i, f := range fields {
+		var err error
+		if v[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return errors.New(fmt.Sprintf("Bad component '%s' for nvector.Coordinate", f))
+		}
+	}
+	c.X, c.Y, c.Z, c.Altitude = v[0], v[1], v[2], latlong.Meters(v[3])
 	return nil
 }
 
@@ -95,3 +158,7 @@ func (c Coordinate) Lon() float64 {
 	point := c.ToLatLong()
 	return point.Longitude
 }
+
+func (c Coordinate) Alt() latlong.Length {
+	return c.Altitude
+}
@@ -0,0 +1,119 @@
+package nvector
+
+import (
+	"latlong"
+	"math"
+)
+
+// Unit n-vector of a LatLonger
+func unit(l latlong.LatLonger) [3]float64 {
+	rlat, rlon := rad(l.Lat()), rad(l.Lon())
+	return [3]float64{
+		math.Cos(rlat) * math.Cos(rlon),
+		math.Cos(rlat) * math.Sin(rlon),
+		math.Sin(rlat),
+	}
+}
+
+// LatLong of a (not necessarily unit) n-vector
+func toLatLong(v [3]float64) latlong.Coordinate {
+	c := Coordinate{X: v[0], Y: v[1], Z: v[2]}
+	return c.ToLatLong()
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func cross(a, b [3]float64) [3]float64 {
+	return [3]float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+func length(a [3]float64) float64 {
+	return math.Sqrt(dot(a, a))
+}
+
+// Interpolate finds the point a fraction f of the way along the great
+// circle from a to b, where f = 0 gives a and f = 1 gives b.
+//
+// Antipodal points are joined by infinitely many great circles, in
+// which case the path over the pole nearest to a is taken.
+func Interpolate(a, b latlong.LatLonger, f float64) latlong.Coordinate {
+	va, vb := unit(a), unit(b)
+	omega := math.Atan2(length(cross(va, vb)), dot(va, vb)) // Angle between a and b
+
+	var p, q [3]float64 // Orthonormal basis of the great circle's plane
+	p = va
+	if s := math.Sin(omega); s > 1e-12 {
+		// q is vb with its component along va removed
+		c := math.Cos(omega)
+		for i := range q {
+			q[i] = (vb[i] - c*va[i]) / s
+		}
+	} else if omega < math.Pi/2 {
+		// Coincident points
+		return toLatLong(va)
+	} else {
+		// Antipodal points: head for the nearest pole
+		pole := [3]float64{0, 0, 1}
+		if va[2] < 0 {
+			pole[2] = -1
+		}
+		q = cross(cross(va, pole), va)
+		if length(q) == 0 {
+			// a is itself a pole, so any meridian will do
+			q = [3]float64{1, 0, 0}
+		}
+	}
+
+	theta := f * omega
+	var v [3]float64
+	for i := range v {
+		v[i] = math.Cos(theta)*p[i] + math.Sin(theta)*q[i]
+	}
+	return toLatLong(v)
+}
+
+// Midpoint of the great circle from a to b
+func Midpoint(a, b latlong.LatLonger) latlong.Coordinate {
+	return Interpolate(a, b, 0.5)
+}
+
+// IntermediatePoints returns n points evenly spaced along the great
+// circle from a to b, not including a and b themselves. There are no
+// points if n is not positive.
+func IntermediatePoints(a, b latlong.LatLonger, n int) []latlong.Coordinate {
+	if n <= 0 {
+		return nil
+	}
+	points := make([]latlong.Coordinate, n)
+	for i := range points {
+		points[i] = Interpolate(a, b, float64(i+1)/float64(n+1))
+	}
+	return points
+}
+
+// Densify inserts evenly spaced great circle points into every leg of
+// a trajectory longer than maxLeg, so that straight lines drawn
+// between consecutive points follow the great circle flown. If maxLeg
+// is not a positive, finite length, the trajectory is returned
+// unchanged.
+func Densify(trajectory []latlong.LatLonger, maxLeg latlong.Length) []latlong.Coordinate {
+	split := maxLeg > 0 && !math.IsInf(float64(maxLeg), 1)
+	var dense []latlong.Coordinate
+	for i, point := range trajectory {
+		if i > 0 && split {
+			prev := trajectory[i-1]
+			n := int(math.Ceil(float64(latlong.Distance(prev, point)/maxLeg))) - 1
+			if n > 0 {
+				dense = append(dense, IntermediatePoints(prev, point, n)...)
+			}
+		}
+		dense = append(dense, latlong.Coordinate{Latitude: point.Lat(), Longitude: point.Lon()})
+	}
+	return dense
+}
@@ -1,6 +1,7 @@
 package nvector
 
 import (
+	"encoding/json"
 	"latlong"
 	"math"
 	"math/rand"
@@ -42,3 +43,103 @@ func TestRandPoints(t *testing.T) {
 
 	}
 }
+
+// Generate 100,000 random pairs of lat/long coordinates and assert
+// that the points interpolated between them are evenly spaced along
+// the great circle.
+func TestRandIntermediatePoints(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		a := &latlong.Coordinate{
+			Latitude:  -90 + rand.Float64()*180,
+			Longitude: -180 + rand.Float64()*360,
+		}
+		b := &latlong.Coordinate{
+			Latitude:  -90 + rand.Float64()*180,
+			Longitude: -180 + rand.Float64()*360,
+		}
+
+		total := latlong.Distance(a, b)
+		points := IntermediatePoints(a, b, 3)
+		prev := latlong.LatLonger(a)
+		for _, p := range append(points, *b) {
+			if d := math.Abs(float64(latlong.Distance(prev, p) - total/4)); d > 0.001 {
+				t.Errorf("Interpolated point is %f m off even spacing", d)
+				t.FailNow()
+			}
+			prev = p
+		}
+	}
+}
+
+// Check midpoints across the antimeridian, between antipodal points
+// and of a densified trajectory, and that bad leg lengths and counts
+// add no points.
+func TestMidpoint(t *testing.T) {
+	got := Midpoint(latlong.Coordinate{Latitude: 0, Longitude: 179}, latlong.Coordinate{Latitude: 0, Longitude: -179})
+	if d := math.Abs(math.Abs(got.Longitude) - 180); d > closeEnough || math.Abs(got.Latitude) > closeEnough {
+		t.Errorf("Midpoint across the antimeridian was %v", got)
+	}
+
+	got = Midpoint(latlong.Coordinate{Latitude: 10, Longitude: 0}, latlong.Coordinate{Latitude: -10, Longitude: 180})
+	if d := math.Abs(got.Latitude - 80); d > closeEnough || math.Abs(got.Longitude-180) > closeEnough {
+		t.Errorf("Midpoint of antipodal points was %v, wanted the path over the north pole", got)
+	}
+
+	trajectory := []latlong.LatLonger{
+		latlong.Coordinate{Latitude: 0, Longitude: 0},
+		latlong.Coordinate{Latitude: 0, Longitude: 10},
+		latlong.Coordinate{Latitude: 0, Longitude: 10.5},
+	}
+	dense := Densify(trajectory, latlong.Kilometers(300))
+	if len(dense) != 6 {
+		t.Errorf("Densified trajectory had %d points, wanted 6", len(dense))
+	}
+
+	// Legs that cannot be split are left alone
+	for _, maxLeg := range []latlong.Length{0, latlong.Kilometers(-300), latlong.Length(math.Inf(1)), latlong.Length(math.NaN())} {
+		if dense := Densify(trajectory, maxLeg); len(dense) != len(trajectory) {
+			t.Errorf("Densifying with legs of at most %v gave %d points, wanted %d", maxLeg, len(dense), len(trajectory))
+		}
+	}
+	for _, n := range []int{0, -1} {
+		if points := IntermediatePoints(trajectory[0], trajectory[1], n); len(points) != 0 {
+			t.Errorf("IntermediatePoints with n = %d gave %v", n, points)
+		}
+	}
+}
+
+// Marshal random n-vectors, half of them with altitudes, to JSON and
+// text and assert that they decode to exactly the original.
+func TestRandMarshal(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		want := ToCoordinate(latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+			Altitude:  latlong.Meters(float64(i%2) * (rand.Float64()*20000 - 500)),
+		})
+
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Coordinate
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", b, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, b, got)
+		}
+
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = Coordinate{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", text, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, text, got)
+		}
+	}
+}
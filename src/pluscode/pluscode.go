@@ -0,0 +1,344 @@
+// Package pluscode encodes positions on earth as Open Location Codes,
+// better known as plus codes, such as 8FVC9G8F+6X, and decodes them to
+// the areas they name
+//
+// A full code names an area anywhere on earth. The first ten digits
+// are five pairs of latitude and longitude digits in base 20, naming
+// areas 20°, 1°, 0.05°, 0.0025° and 0.000125° on a side. Each further
+// digit, up to fifteen, splits the last area into a grid of 4 by 5.
+// A plus sign follows the eighth digit, and codes of fewer than eight
+// digits are padded with zeros up to it.
+//
+// A short code leaves out some of the leading digits, such as 9G8F+6X,
+// and names an area only relative to a nearby reference location.
+//
+// Reference for plus codes can be found here: https://github.com/google/open-location-code/blob/main/Documentation/Specification/olc_definition.adoc
+package pluscode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+	"strings"
+)
+
+// Digits of a plus code, in order of value
+const alphabet = "23456789CFGHJMPQRVWX"
+
+const (
+	separator         = '+'
+	separatorPosition = 8
+	padding           = '0'
+
+	// Digits that come in latitude/longitude pairs
+	pairLength = 10
+
+	// Rows and columns each grid digit splits an area into
+	gridRows    = 5
+	gridColumns = 4
+
+	// Units of the finest area per degree
+	latitudeUnits  = 8000 * 5 * 5 * 5 * 5 * 5
+	longitudeUnits = 8000 * 4 * 4 * 4 * 4 * 4
+)
+
+// MaxLength is the most digits a code can have. Fifteen digits name an
+// area a few millimeters across.
+const MaxLength = 15
+
+// Encode finds the full plus code of point with the given number of
+// digits, which is clamped to [2, MaxLength] and, below 10, rounded
+// down to an even number. As in the reference implementation,
+// latitudes out of range are clamped to the poles and longitudes are
+// wrapped into range, but a latitude or longitude that is not a finite
+// number is rejected with a *latlong.RangeError.
+func Encode(point latlong.LatLonger, length int) (string, error) {
+	if length > MaxLength {
+		length = MaxLength
+	}
+	if length < 2 {
+		length = 2
+	}
+	if length < pairLength && length%2 == 1 {
+		length--
+	}
+
+	// Work in whole units of the finest area, as the reference
+	// implementation does, offset from the south pole and the
+	// antimeridian
+	lat, lon := point.Lat(), point.Lon()
+	if math.IsNaN(lat) || math.IsInf(lat, 0) {
+		return "", &latlong.RangeError{Field: "Latitude", Value: lat}
+	}
+	if math.IsNaN(lon) || math.IsInf(lon, 0) {
+		return "", &latlong.RangeError{Field: "Longitude", Value: lon}
+	}
+	lat = math.Max(-90, math.Min(90, lat))
+	latVal := toUnits(lat, latitudeUnits) + 90*latitudeUnits
+	if latVal >= 180*latitudeUnits {
+		latVal = 180*latitudeUnits - 1 // The north pole is in the top row
+	}
+	if math.Abs(lon) > 180 {
+		lon = math.Remainder(lon, 360) // Exact, so edges stay edges
+	}
+	lonVal := toUnits(lon, longitudeUnits) + 180*longitudeUnits
+	lonVal %= 360 * longitudeUnits
+	if lonVal < 0 {
+		lonVal += 360 * longitudeUnits
+	}
+
+	digits := make([]byte, MaxLength)
+	for i := MaxLength - 1; i >= pairLength; i-- {
+		digits[i] = alphabet[latVal%gridRows*gridColumns+lonVal%gridColumns]
+		latVal /= gridRows
+		lonVal /= gridColumns
+	}
+	for i := pairLength - 2; i >= 0; i -= 2 {
+		digits[i] = alphabet[latVal%20]
+		digits[i+1] = alphabet[lonVal%20]
+		latVal /= 20
+		lonVal /= 20
+	}
+
+	if length < separatorPosition {
+		return string(digits[:length]) + strings.Repeat(string(padding), separatorPosition-length) + string(separator), nil
+	}
+	return string(digits[:separatorPosition]) + string(separator) + string(digits[separatorPosition:length]), nil
+}
+
+// toUnits counts the whole units of the finest area in degrees,
+// rounding away float error in the last millionth of a unit first, so
+// that a point written on the edge of an area, such as a latitude of
+// 20.000125, is counted in the area it starts. The offsets are added
+// afterwards, since adding them in degrees loses that precision.
+func toUnits(degrees, units float64) int64 {
+	return int64(math.Floor(math.Floor(degrees*units*1e6+0.5) / 1e6))
+}
+
+// IsValid reports whether code is a valid full or short plus code.
+// Upper and lower case are both accepted.
+func IsValid(code string) bool {
+	sep := strings.IndexRune(code, separator)
+	if sep < 0 || sep != strings.LastIndex(code, string(separator)) || len(code) == 1 {
+		return false
+	}
+	if sep > separatorPosition || sep%2 == 1 {
+		return false
+	}
+
+	// Padding is a single even run of zeros that fills out a full code
+	// up to the separator
+	if pad := strings.IndexRune(code, padding); pad >= 0 {
+		run := strings.TrimLeft(code[pad:], string(padding))
+		zeros := len(code) - pad - len(run)
+		if sep < separatorPosition || pad == 0 || pad%2 == 1 || zeros%2 == 1 || run != string(separator) {
+			return false
+		}
+	}
+
+	// One digit on its own after the separator is not allowed
+	if len(code)-sep-1 == 1 {
+		return false
+	}
+	for _, r := range strings.ToUpper(code) {
+		if r != separator && r != padding && !strings.ContainsRune(alphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsShort reports whether code is a valid short plus code
+func IsShort(code string) bool {
+	return IsValid(code) && strings.IndexRune(code, separator) < separatorPosition
+}
+
+// IsFull reports whether code is a valid full plus code
+func IsFull(code string) bool {
+	if !IsValid(code) || IsShort(code) {
+		return false
+	}
+	// The first pair must be on the earth
+	upper := strings.ToUpper(code)
+	return strings.IndexByte(alphabet, upper[0])*20 < 180 && strings.IndexByte(alphabet, upper[1])*20 < 360
+}
+
+// Area is the region of the earth named by a full plus code
+type Area struct {
+	Code           string // In upper case, e.g. 8FVC9G8F+6X
+	Center         latlong.Coordinate
+	LatitudeError  float64 // Half the height of the area, in degrees
+	LongitudeError float64 // Half the width of the area, in degrees
+}
+
+// Bounds of the area
+func (a Area) Bounds() latlong.BoundingBox {
+	return latlong.BoundingBox{
+		South: a.Center.Latitude - a.LatitudeError,
+		North: a.Center.Latitude + a.LatitudeError,
+		West:  a.Center.Longitude - a.LongitudeError,
+		East:  a.Center.Longitude + a.LongitudeError,
+	}
+}
+
+// Length gives the number of digits in the code of the area
+func (a Area) Length() int {
+	return len(strings.TrimRight(strings.Replace(a.Code, string(separator), "", 1), string(padding)))
+}
+
+// Decode finds the area a full plus code names. Digits beyond
+// MaxLength are ignored. Short codes must first be recovered with
+// RecoverNearest.
+func Decode(code string) (Area, error) {
+	if !IsFull(code) {
+		if IsShort(code) {
+			return Area{}, errors.New(fmt.Sprintf("Plus code '%s' is short, and needs a reference location", code))
+		}
+		return Area{}, errors.New(fmt.Sprintf("'%s' is not a valid full plus code", code))
+	}
+	code = strings.ToUpper(code)
+	digits := strings.TrimRight(strings.Replace(code, string(separator), "", 1), string(padding))
+	if len(digits) > MaxLength {
+		digits = digits[:MaxLength]
+	}
+
+	// Sum the digits in units of the finest area, keeping track of the
+	// size of the area named by the last. Places start at 400°, so that
+	// the first pair is worth 20°.
+	var lat, lon int64
+	latPlace, lonPlace := int64(400*latitudeUnits), int64(400*longitudeUnits)
+	for i := 0; i < len(digits); i++ {
+		v := int64(strings.IndexByte(alphabet, digits[i]))
+		switch {
+		case i >= pairLength:
+			latPlace /= gridRows
+			lonPlace /= gridColumns
+			lat += v / gridColumns * latPlace
+			lon += v % gridColumns * lonPlace
+		case i%2 == 0:
+			latPlace /= 20
+			lonPlace /= 20
+			lat += v * latPlace
+		default:
+			lon += v * lonPlace
+		}
+	}
+
+	height := float64(latPlace) / latitudeUnits
+	width := float64(lonPlace) / longitudeUnits
+	return Area{
+		Code: code,
+		Center: latlong.Coordinate{
+			Latitude:  float64(lat)/latitudeUnits - 90 + height/2,
+			Longitude: float64(lon)/longitudeUnits - 180 + width/2,
+		},
+		LatitudeError:  height / 2,
+		LongitudeError: width / 2,
+	}, nil
+}
+
+// Shorten removes as many leading digits from a full code as a
+// reference location close to it allows, leaving a short code that
+// RecoverNearest can restore from any location near the reference
+func Shorten(code string, reference latlong.LatLonger) (string, error) {
+	area, err := Decode(code)
+	if err != nil {
+		return "", err
+	}
+	if strings.IndexRune(code, padding) >= 0 {
+		return "", errors.New(fmt.Sprintf("Cannot shorten padded plus code '%s'", code))
+	}
+
+	// Shorten only well within half an area of the reference, so that
+	// locations a little further away still recover the same code
+	lat := math.Max(-90, math.Min(90, reference.Lat()))
+	dLon := math.Mod(math.Abs(area.Center.Longitude-reference.Lon()), 360)
+	dist := math.Max(math.Abs(area.Center.Latitude-lat), math.Min(dLon, 360-dLon))
+	for removed, size := 8, 0.0025; removed >= 4; removed, size = removed-2, size*20 {
+		if dist < size*0.3 {
+			return area.Code[removed:], nil
+		}
+	}
+	return area.Code, nil
+}
+
+// RecoverNearest finds the full code nearest to the reference location
+// that ends with the short code. Full codes are returned unchanged,
+// though in upper case.
+func RecoverNearest(short string, reference latlong.LatLonger) (string, error) {
+	if IsFull(short) {
+		return strings.ToUpper(short), nil
+	}
+	if !IsShort(short) {
+		return "", errors.New(fmt.Sprintf("'%s' is not a valid short plus code", short))
+	}
+
+	// Take the missing digits from the reference, then move by one of
+	// the areas they name if that brings the code closer to it
+	lat := math.Max(-90, math.Min(90, reference.Lat()))
+	lon := math.Mod(reference.Lon()+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	lon -= 180
+	missing := separatorPosition - strings.IndexRune(short, separator)
+	size := math.Pow(20, float64(2-missing/2))
+	code, err := Encode(reference, pairLength)
+	if err != nil {
+		return "", err
+	}
+	area, err := Decode(code[:missing] + short)
+	if err != nil {
+		return "", err
+	}
+
+	center := area.Center
+	if lat+size/2 < center.Latitude && center.Latitude-size >= -90 {
+		center.Latitude -= size
+	} else if lat-size/2 > center.Latitude && center.Latitude+size <= 90 {
+		center.Latitude += size
+	}
+	if lon+size/2 < center.Longitude {
+		center.Longitude -= size
+	} else if lon-size/2 > center.Longitude {
+		center.Longitude += size
+	}
+	return Encode(center, area.Length())
+}
+
+// UnmarshalJSON decodes a JSON string holding a full plus code
+func (a *Area) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("Plus code must be a JSON string")
+	}
+	decoded, err := Decode(s)
+	if err != nil {
+		return err
+	}
+	*a = decoded
+	return nil
+}
+
+// MarshalJSON encodes the area as a JSON string holding its code
+func (a Area) MarshalJSON() ([]byte, error) {
+	if _, err := Decode(a.Code); err != nil {
+		return nil, err
+	}
+	return json.Marshal(a.Code)
+}
+
+// String gives the code of the area
+func (a Area) String() string {
+	return a.Code
+}
+
+func (a Area) Lat() float64 {
+	return a.Center.Latitude
+}
+
+func (a Area) Lon() float64 {
+	return a.Center.Longitude
+}
@@ -0,0 +1,262 @@
+package pluscode
+
+import (
+	"encoding/json"
+	"latlong"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between floating point values
+)
+
+// Known codes, from the test data of the reference implementation.
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		point  latlong.Coordinate
+		length int
+		code   string
+	}{
+		{latlong.Coordinate{Latitude: 20.375, Longitude: 2.775}, 6, "7FG49Q00+"},
+		{latlong.Coordinate{Latitude: 20.3700625, Longitude: 2.7821875}, 10, "7FG49QCJ+2V"},
+		{latlong.Coordinate{Latitude: 20.3701125, Longitude: 2.782234375}, 11, "7FG49QCJ+2VX"},
+		{latlong.Coordinate{Latitude: 20.3701135, Longitude: 2.78223535156}, 13, "7FG49QCJ+2VXGJ"},
+		{latlong.Coordinate{Latitude: 47.0000625, Longitude: 8.0000625}, 10, "8FVC2222+22"},
+		{latlong.Coordinate{Latitude: -41.2730625, Longitude: 174.7859375}, 10, "4VCPPQGP+Q9"},
+		{latlong.Coordinate{Latitude: 0.5, Longitude: -179.5}, 4, "62G20000+"},
+		{latlong.Coordinate{Latitude: -89.5, Longitude: -179.5}, 4, "22220000+"},
+		{latlong.Coordinate{Latitude: -89.9999375, Longitude: -179.9999375}, 10, "22222222+22"},
+		{latlong.Coordinate{Latitude: 0.5, Longitude: 179.5}, 4, "6VGX0000+"},
+		{latlong.Coordinate{Latitude: 1, Longitude: 1}, 11, "6FH32222+222"},
+		{latlong.Coordinate{Latitude: 90, Longitude: 1}, 4, "CFX30000+"},
+		{latlong.Coordinate{Latitude: 92, Longitude: 1}, 4, "CFX30000+"},
+		{latlong.Coordinate{Latitude: 1, Longitude: 181}, 4, "62H30000+"},
+		{latlong.Coordinate{Latitude: 1, Longitude: 1}, 5, "6FH30000+"},
+	}
+	for _, c := range cases {
+		if got, err := Encode(c.point, c.length); err != nil || got != c.code {
+			t.Errorf("Encode(%v, %d) was %s, %v, wanted %s", c.point, c.length, got, err, c.code)
+		}
+	}
+}
+
+// Check that positions that are not finite are rejected, rather than
+// clamped or wrapped into an area.
+func TestEncodeNaN(t *testing.T) {
+	bad := []latlong.Coordinate{
+		{Latitude: math.NaN(), Longitude: 0},
+		{Latitude: 0, Longitude: math.NaN()},
+		{Latitude: math.Inf(1), Longitude: 0},
+		{Latitude: 0, Longitude: math.Inf(-1)},
+	}
+	for _, point := range bad {
+		if got, err := Encode(point, 10); err == nil {
+			t.Errorf("Encode(%v, 10) was %s, wanted an error", point, got)
+		}
+	}
+	if full, err := RecoverNearest("9G8F+6X", latlong.Coordinate{Latitude: math.NaN()}); err == nil {
+		t.Errorf("Recovered %s near NaN", full)
+	}
+}
+
+// Points written exactly on the southwest edge of an area, which must
+// encode in that area rather than the one below or to the west of it.
+func TestEncodeEdges(t *testing.T) {
+	cases := []struct {
+		point  latlong.Coordinate
+		length int
+		code   string
+	}{
+		{latlong.Coordinate{Latitude: 20.000125, Longitude: 2.000125}, 10, "7FG42222+33"},
+		{latlong.Coordinate{Latitude: -20.000125, Longitude: -2.000125}, 10, "5CFVXXXX+XX"},
+		{latlong.Coordinate{Latitude: 81.800125, Longitude: 10.5}, 10, "CFHGRG22+32"},
+		{latlong.Coordinate{Latitude: 81.835, Longitude: -60.25}, 11, "C7HXRQP2+222"},
+		{latlong.Coordinate{Latitude: -41.0001875, Longitude: 174.000125}, 11, "4VCPX2X2+W3C"},
+		{latlong.Coordinate{Latitude: 0.05, Longitude: -0.0025}, 6, "6CGX3X00+"},
+	}
+	for _, c := range cases {
+		if got, err := Encode(c.point, c.length); err != nil || got != c.code {
+			t.Errorf("Encode(%v, %d) was %s, %v, wanted %s", c.point, c.length, got, err, c.code)
+		}
+	}
+
+	// Every latitude on the edge of a row of ten digit areas
+	for i := -720000; i < 720000; i++ {
+		lat, err := strconv.ParseFloat(strconv.FormatFloat(float64(i)*0.000125, 'f', 6, 64), 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := Encode(latlong.Coordinate{Latitude: lat, Longitude: 0}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		area, err := Decode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if south := area.Center.Latitude - area.LatitudeError; math.Abs(south-lat) > closeEnough {
+			t.Fatalf("%f encodes as %s, which starts at %f", lat, area.Code, south)
+		}
+	}
+}
+
+// Check validity, from the test data of the reference implementation.
+func TestValid(t *testing.T) {
+	cases := []struct {
+		code               string
+		valid, short, full bool
+	}{
+		{"8FWC2345+G6", true, false, true},
+		{"8FWC2345+G6G", true, false, true},
+		{"8fwc2345+", true, false, true},
+		{"8FWCX400+", true, false, true},
+		{"WC2345+G6g", true, true, false},
+		{"2345+G6", true, true, false},
+		{"45+G6", true, true, false},
+		{"+G6", true, true, false},
+		{"V2000000+", true, false, false},
+		{"G+", false, false, false},
+		{"+", false, false, false},
+		{"8FWC2345+G", false, false, false},
+		{"8FWC2_45+G6", false, false, false},
+		{"8FWC2η45+G6", false, false, false},
+		{"8FWC2345+G6+", false, false, false},
+		{"8FWC2345G6+", false, false, false},
+		{"8FWC2300+G6", false, false, false},
+		{"WC2300+G6g", false, false, false},
+		{"WC2345+G", false, false, false},
+		{"WC2300+", false, false, false},
+		{"8FW00000+", false, false, false},
+	}
+	for _, c := range cases {
+		if IsValid(c.code) != c.valid || IsShort(c.code) != c.short || IsFull(c.code) != c.full {
+			t.Errorf("%s: valid %t, short %t, full %t, wanted %t, %t, %t",
+				c.code, IsValid(c.code), IsShort(c.code), IsFull(c.code), c.valid, c.short, c.full)
+		}
+	}
+}
+
+// Check shortening and recovery, from the test data of the reference
+// implementation.
+func TestShorten(t *testing.T) {
+	cases := []struct {
+		code      string
+		reference latlong.Coordinate
+		short     string
+	}{
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.3701125, Longitude: -1.217765625}, "+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.3708675, Longitude: -1.217765625}, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.3693575, Longitude: -1.217765625}, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.3701125, Longitude: -1.218520625}, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.3701125, Longitude: -1.217010625}, "CJ+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.3852125, Longitude: -1.217765625}, "9QCJ+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 51.6, Longitude: -1.217765625}, "9QCJ+2VX"},
+		{"9C3W9QCJ+2VX", latlong.Coordinate{Latitude: 53, Longitude: -1.217765625}, "9C3W9QCJ+2VX"},
+	}
+	for _, c := range cases {
+		short, err := Shorten(c.code, c.reference)
+		if err != nil || short != c.short {
+			t.Errorf("Shorten(%s, %v) was %s, %v, wanted %s", c.code, c.reference, short, err, c.short)
+		}
+		if full, err := RecoverNearest(c.short, c.reference); err != nil || full != c.code {
+			t.Errorf("RecoverNearest(%s, %v) was %s, %v, wanted %s", c.short, c.reference, full, err, c.code)
+		}
+	}
+
+	// Recovery finds the nearest match, even across the antimeridian
+	// and next to the poles
+	recoveries := []struct {
+		short     string
+		reference latlong.Coordinate
+		code      string
+	}{
+		{"2222+22", latlong.Coordinate{Latitude: 1, Longitude: 179.9}, "62H22222+22"},
+		{"XXXX+XX", latlong.Coordinate{Latitude: 89.6, Longitude: 0.1}, "CCXXXXXX+XX"},
+		{"2222+22", latlong.Coordinate{Latitude: 89.6, Longitude: 0.1}, "CFX22222+22"},
+	}
+	for _, c := range recoveries {
+		if full, err := RecoverNearest(c.short, c.reference); err != nil || full != c.code {
+			t.Errorf("RecoverNearest(%s, %v) was %s, %v, wanted %s", c.short, c.reference, full, err, c.code)
+		}
+	}
+
+	if _, err := Shorten("8FWC0000+", latlong.Coordinate{Latitude: 47, Longitude: 8}); err == nil {
+		t.Errorf("Shortening a padded code succeeded")
+	}
+}
+
+// Generate 100,000 random lat/long coordinates, encode them at random
+// lengths, decode them, and assert that the area holds the original
+// point and encodes back to the same code, and that short codes of it
+// recover from nearby.
+func TestRandDecode(t *testing.T) {
+	lengths := []int{2, 4, 6, 8, 10, 11, 12, 13, 14, 15}
+	for i := 0; i < 100000; i++ {
+		point := latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+		}
+		code, err := Encode(point, lengths[rand.Intn(len(lengths))])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		area, err := Decode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !area.Bounds().Contains(point) {
+			t.Errorf("Area %+v of %s does not hold %v", area, code, point)
+		}
+		if got, _ := Encode(area, area.Length()); got != code || area.Code != code {
+			t.Errorf("Center of %s encodes as %s", area, got)
+		}
+
+		if area.Length() < pairLength {
+			continue
+		}
+		short, err := Shorten(code, point)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full, err := RecoverNearest(short, point); err != nil || full != code {
+			t.Errorf("%s shortened to %s near %v, which recovers as %s, %v", code, short, point, full, err)
+		}
+	}
+}
+
+// Check the area decoded from a known code, and that short codes
+// cannot be decoded alone.
+func TestDecode(t *testing.T) {
+	area, err := Decode("7fg49qcj+2v")
+	if err != nil || area.Code != "7FG49QCJ+2V" {
+		t.Fatalf("Decoding 7fg49qcj+2v gave %+v, %v", area, err)
+	}
+	if math.Abs(area.Center.Latitude-20.3700625) > closeEnough || math.Abs(area.Center.Longitude-2.7821875) > closeEnough {
+		t.Errorf("Center of %s is %v", area, area.Center)
+	}
+	if math.Abs(area.LatitudeError-0.0000625) > closeEnough || math.Abs(area.LongitudeError-0.0000625) > closeEnough {
+		t.Errorf("%s is %f° by %f°", area, 2*area.LatitudeError, 2*area.LongitudeError)
+	}
+	if area, err := Decode("9QCJ+2VX"); err == nil {
+		t.Errorf("Decoding a short code gave %+v", area)
+	}
+}
+
+// Check that areas marshal and unmarshal as JSON strings.
+func TestMarshal(t *testing.T) {
+	var a Area
+	if err := json.Unmarshal([]byte(`"8fvc9g8f+6x"`), &a); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(a)
+	if err != nil || string(b) != `"8FVC9G8F+6X"` {
+		t.Errorf("%+v marshaled as %s, %v", a, b, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Latitude": 1, "Longitude": 2}`), &a); err == nil {
+		t.Errorf("Unmarshaling an object as a plus code succeeded")
+	}
+}
@@ -0,0 +1,158 @@
+package projection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+)
+
+// Coordinate is a point on the plane of a registered projection. Its
+// position on the earth is found once, when it is made by NewCoordinate
+// or unmarshaled, so it is always on its projection. The zero value has
+// no projection, and its Lat and Lon are NaN.
+type Coordinate struct {
+	easting    float64
+	northing   float64
+	projection Projection
+	altitude   latlong.Length // Height above the reference ellipsoid
+	position   latlong.Coordinate
+}
+
+// NewCoordinate places an easting and northing on a projection, at an
+// altitude, finding its position by the inverse of the projection. It
+// fails if the point is not on the projection.
+func NewCoordinate(easting, northing float64, p Projection, altitude latlong.Length) (Coordinate, error) {
+	if p == nil {
+		return Coordinate{}, errors.New("Coordinate has no projection")
+	}
+	position, err := p.Inverse(Point{easting, northing})
+	if err != nil {
+		return Coordinate{}, err
+	}
+	position.Altitude = altitude
+	return Coordinate{easting, northing, p, altitude, position}, nil
+}
+
+// Point gives the easting and northing of the coordinate
+func (c Coordinate) Point() Point {
+	return Point{c.easting, c.northing}
+}
+
+// Projection the coordinate is on
+func (c Coordinate) Projection() Projection {
+	return c.projection
+}
+
+// ToLatLong gives the latitude, longitude and altitude of the
+// coordinate, found by the inverse of its projection
+func (c Coordinate) ToLatLong() (latlong.Coordinate, error) {
+	if c.projection == nil {
+		return latlong.Coordinate{}, errors.New("Coordinate has no projection")
+	}
+	return c.position, nil
+}
+
+func (c *Coordinate) UnmarshalJSON(b []byte) error {
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+
+	// Check number of fields in JSON object. The Altitude field is optional.
+	_, hasAltitude := obj["Altitude"]
+	if len(obj) > 4 || (len(obj) == 4 && !hasAltitude) {
+		return errors.New(fmt.Sprintf("Too many fields for projection.Coordinate"))
+	}
+	if len(obj) < 3 {
+		return errors.New(fmt.Sprintf("Not enough fields for projection.Coordinate"))
+	}
+
+	// Check Easting
+	if _, ok := obj["Easting"]; !ok {
+		return errors.New("Missing field 'Easting'")
+	}
+	if _, ok := obj["Easting"].(float64); !ok {
+		return errors.New("Wrong type for field 'Easting'")
+	}
+
+	// Check Northing
+	if _, ok := obj["Northing"]; !ok {
+		return errors.New("Missing field 'Northing'")
+	}
+	if _, ok := obj["Northing"].(float64); !ok {
+		return errors.New("Wrong type for field 'Northing'")
+	}
+
+	// Check Projection
+	if _, ok := obj["Projection"]; !ok {
+		return errors.New("Missing field 'Projection'")
+	}
+	name, ok := obj["Projection"].(string)
+	if !ok {
+		return errors.New("Wrong type for field 'Projection'")
+	}
+	p, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	// Check Altitude
+	var altitude float64
+	if hasAltitude {
+		var ok bool
+		if altitude, ok = obj["Altitude"].(float64); !ok {
+			return errors.New("Wrong type for field 'Altitude'")
+		}
+	}
+
+	// Check that the point is on the projection
+	parsed, err := NewCoordinate(obj["Easting"].(float64), obj["Northing"].(float64), p, latlong.Meters(altitude))
+	if err != nil {
+		return err
+	}
+
+	// All clear
+	*c = parsed
+	return nil
+}
+
+// MarshalJSON encodes the coordinate as an object with the fields
+// UnmarshalJSON requires, naming the projection by its EPSG code if it
+// has one, and giving its altitude in meters if it is not zero
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	if c.projection == nil {
+		return nil, errors.New("Coordinate has no projection")
+	}
+	if math.IsNaN(c.easting+c.northing) || math.IsInf(c.easting+c.northing, 0) {
+		return nil, errors.New("Cannot marshal a projection.Coordinate that is not finite")
+	}
+	name := c.projection.Name()
+	if c.projection.EPSG() != 0 {
+		name = fmt.Sprintf("EPSG:%d", c.projection.EPSG())
+	}
+	return json.Marshal(struct {
+		Easting, Northing float64
+		Projection        string
+		Altitude          float64 `json:",omitempty"`
+	}{c.easting, c.northing, name, c.altitude.Meters()})
+}
+
+func (c Coordinate) Lat() float64 {
+	if c.projection == nil {
+		return math.NaN()
+	}
+	return c.position.Latitude
+}
+
+func (c Coordinate) Lon() float64 {
+	if c.projection == nil {
+		return math.NaN()
+	}
+	return c.position.Longitude
+}
+
+func (c Coordinate) Alt() latlong.Length {
+	return c.altitude
+}
@@ -0,0 +1,117 @@
+// Package projection defines map projections, which carry positions on
+// the earth to coordinates in meters on a plane and back, and keeps a
+// registry of them by name and EPSG code
+//
+// Packages providing projections register them from their init
+// functions, as package utm does for each of its zones, so a program
+// finds them by Lookup and reads their coordinates with Unmarshal once
+// it imports the package.
+//
+// Reference for EPSG codes can be found here: https://epsg.org
+package projection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"strconv"
+	"strings"
+)
+
+// Projection maps positions on the earth onto a plane and back
+type Projection interface {
+	// Name of the projection, e.g. "UTM zone 33N"
+	Name() string
+	// EPSG code of the projected coordinate system, or 0 if it has none
+	EPSG() int
+	// Forward projects a position on the earth onto the plane
+	Forward(point latlong.LatLonger) (Point, error)
+	// Inverse finds the position on the earth of a point on the plane
+	Inverse(p Point) (latlong.Coordinate, error)
+}
+
+// Point is a position on the plane of a projection, in meters
+type Point struct {
+	Easting, Northing float64
+}
+
+var (
+	// Registered projections, by lowercase name and by EPSG code
+	byName = map[string]Projection{}
+	byEPSG = map[int]Projection{}
+
+	// Coordinate types of their own that projections have registered,
+	// in the order they were registered
+	formats []func() latlong.LatLonger
+)
+
+// Register adds a projection to the registry, so that Lookup finds it
+// by its name and EPSG code. Names are compared ignoring case, and must
+// be unique, as must EPSG codes other than 0.
+func Register(p Projection) error {
+	name := strings.ToLower(p.Name())
+	if name == "" {
+		return errors.New("Projection must have a name")
+	}
+	if _, ok := byName[name]; ok {
+		return errors.New(fmt.Sprintf("Projection '%s' is already registered", p.Name()))
+	}
+	if _, ok := byEPSG[p.EPSG()]; ok && p.EPSG() != 0 {
+		return errors.New(fmt.Sprintf("Projection with EPSG code %d is already registered", p.EPSG()))
+	}
+
+	byName[name] = p
+	if p.EPSG() != 0 {
+		byEPSG[p.EPSG()] = p
+	}
+	return nil
+}
+
+// RegisterFormat adds a coordinate type of a projection's own, such as
+// utm.Coordinate with its zone number and letter, for Unmarshal to try.
+// newCoordinate returns a new, empty value of the type, which must
+// unmarshal from JSON.
+func RegisterFormat(newCoordinate func() latlong.LatLonger) {
+	formats = append(formats, newCoordinate)
+}
+
+// Lookup finds a registered projection by name, ignoring case, or by
+// its EPSG code written as e.g. "EPSG:32633"
+func Lookup(name string) (Projection, error) {
+	if p, ok := byName[strings.ToLower(name)]; ok {
+		return p, nil
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "EPSG:") {
+		if code, err := strconv.Atoi(name[len("EPSG:"):]); err == nil {
+			if p, ok := byEPSG[code]; ok {
+				return p, nil
+			}
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown projection '%s'", name))
+}
+
+// Unmarshal decodes a JSON encoded coordinate on a registered
+// projection: a Coordinate, which names its projection, or a value of
+// one of the types added by RegisterFormat
+func Unmarshal(b []byte) (latlong.LatLonger, error) {
+	obj := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &obj); err == nil {
+		if _, ok := obj["Projection"]; ok {
+			c := new(Coordinate)
+			if err := json.Unmarshal(b, c); err != nil {
+				return nil, err
+			}
+			return c, nil
+		}
+	}
+
+	for _, newCoordinate := range formats {
+		c := newCoordinate()
+		if err := json.Unmarshal(b, c); err == nil {
+			return c, nil
+		}
+	}
+	return nil, errors.New("Not a coordinate on any registered projection")
+}
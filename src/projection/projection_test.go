@@ -0,0 +1,184 @@
+package projection
+
+import (
+	"encoding/json"
+	"errors"
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	closeEnough = 0.00000001 // Maximum difference between floating point values
+)
+
+// plateCarree is the equirectangular projection on a sphere, simple
+// enough to check the registry with
+type plateCarree struct{}
+
+// Meters per degree along the equator
+const meters = 6371008.8 * math.Pi / 180
+
+func (plateCarree) Name() string { return "Plate carree" }
+func (plateCarree) EPSG() int    { return 0 }
+
+func (plateCarree) Forward(point latlong.LatLonger) (Point, error) {
+	return Point{point.Lon() * meters, point.Lat() * meters}, nil
+}
+
+func (plateCarree) Inverse(p Point) (latlong.Coordinate, error) {
+	c := latlong.Coordinate{Latitude: p.Northing / meters, Longitude: p.Easting / meters}
+	if err := c.Validate(); err != nil {
+		return latlong.Coordinate{}, err
+	}
+	return c, nil
+}
+
+// pseudo is registered under an EPSG code, with a coordinate type of its
+// own
+type pseudo struct{ plateCarree }
+
+func (pseudo) Name() string { return "Pseudo plate carree" }
+func (pseudo) EPSG() int    { return 99999 }
+
+type pseudoCoordinate struct{ latlong.Coordinate }
+
+func (c *pseudoCoordinate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil || s != "origin" {
+		return errors.New("Not the origin")
+	}
+	return nil
+}
+
+func init() {
+	if err := Register(plateCarree{}); err != nil {
+		panic(err)
+	}
+	if err := Register(pseudo{}); err != nil {
+		panic(err)
+	}
+	RegisterFormat(func() latlong.LatLonger { return new(pseudoCoordinate) })
+}
+
+// Check that projections are found by name and EPSG code, and that
+// names and codes cannot be registered twice.
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"plate carree", "PLATE CARREE", "Pseudo plate carree", "epsg:99999"} {
+		if _, err := Lookup(name); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, name := range []string{"", "EPSG:0", "EPSG:", "Mercator"} {
+		if p, err := Lookup(name); err == nil {
+			t.Errorf("Lookup(%s) found %s", name, p.Name())
+		}
+	}
+	if err := Register(plateCarree{}); err == nil {
+		t.Errorf("Registering a name twice succeeded")
+	}
+	if err := Register(struct{ pseudo }{}); err == nil {
+		t.Errorf("Registering an EPSG code twice succeeded")
+	}
+}
+
+// Generate 100,000 random lat/long coordinates, project them, marshal
+// and unmarshal them as Coordinates, and assert that we got something
+// close enough to the original.
+func TestRandMarshal(t *testing.T) {
+	p, _ := Lookup("Plate carree")
+	for i := 0; i < 100000; i++ {
+		want := latlong.Coordinate{
+			Latitude:  rand.Float64()*180 - 90,
+			Longitude: rand.Float64()*360 - 180,
+			Altitude:  latlong.Meters(float64(i % 2 * 100)),
+		}
+		point, err := p.Forward(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewCoordinate(point.Easting, point.Northing, p, want.Altitude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Unmarshal(b)
+		if err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", b, err)
+		}
+		if d := math.Abs(want.Latitude - got.Lat()); d > closeEnough {
+			t.Fatalf("Difference in latitude (%g) outside of acceptable range (%g)", d, closeEnough)
+		}
+		if d := math.Abs(want.Longitude - got.Lon()); d > closeEnough {
+			t.Fatalf("Difference in longitude (%g) outside of acceptable range (%g)", d, closeEnough)
+		}
+		if latlong.Altitude(got) != want.Altitude {
+			t.Fatalf("%s unmarshaled at altitude %v", b, latlong.Altitude(got))
+		}
+	}
+}
+
+// Check that Unmarshal reads registered formats, and rejects
+// coordinates that are off their projection.
+func TestUnmarshal(t *testing.T) {
+	if l, err := Unmarshal([]byte(`"origin"`)); err != nil {
+		t.Errorf("Unmarshaling a registered format failed: %s", err)
+	} else if _, ok := l.(*pseudoCoordinate); !ok {
+		t.Errorf("Unmarshaling a registered format gave %v", l)
+	}
+	if l, err := Unmarshal([]byte(`{"Easting": 0, "Northing": 0, "Projection": "EPSG:99999"}`)); err != nil {
+		t.Errorf("Unmarshaling by EPSG code failed: %s", err)
+	} else if c := l.(*Coordinate); c.Projection().Name() != "Pseudo plate carree" {
+		t.Errorf("Unmarshaling by EPSG code gave %+v", c)
+	}
+
+	bad := []string{
+		`"nowhere"`,
+		`{"Easting": 0, "Northing": 0}`,
+		`{"Easting": 0, "Northing": 0, "Projection": "Mercator"}`,
+		`{"Easting": 0, "Northing": 1e8, "Projection": "Plate carree"}`,
+		`{"Easting": "0", "Northing": 0, "Projection": "Plate carree"}`,
+		`{"Easting": 0, "Northing": 0, "Projection": "Plate carree", "Zone": 1}`,
+	}
+	for _, s := range bad {
+		if l, err := Unmarshal([]byte(s)); err == nil {
+			t.Errorf("Unmarshaling %s gave %v", s, l)
+		}
+	}
+}
+
+// Check that coordinates are only made on their projection, and that
+// the zero value has no position.
+func TestNewCoordinate(t *testing.T) {
+	p, _ := Lookup("Plate carree")
+	c, err := NewCoordinate(2*meters, 1*meters, p, latlong.Meters(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Point() != (Point{2 * meters, 1 * meters}) || c.Projection() != p || c.Lat() != 1 || c.Lon() != 2 || c.Alt() != latlong.Meters(10) {
+		t.Errorf("Made %+v at %f, %f", c, c.Lat(), c.Lon())
+	}
+
+	if c, err := NewCoordinate(0, 1e8, p, 0); err == nil {
+		t.Errorf("Made %+v off the projection", c)
+	}
+	if c, err := NewCoordinate(0, 0, nil, 0); err == nil {
+		t.Errorf("Made %+v with no projection", c)
+	}
+
+	var zero Coordinate
+	if !math.IsNaN(zero.Lat()) || !math.IsNaN(zero.Lon()) {
+		t.Errorf("Zero value is at %f, %f", zero.Lat(), zero.Lon())
+	}
+	if _, err := zero.ToLatLong(); err == nil {
+		t.Error("Zero value has a position")
+	}
+	if b, err := json.Marshal(zero); err == nil {
+		t.Errorf("Zero value marshaled as %s", b)
+	}
+}
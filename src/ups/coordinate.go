@@ -0,0 +1,379 @@
+// Package ups converts between latitude/longitude and Universal Polar
+// Stereographic (UPS) coordinates, the grid that takes over from UTM
+// north of 84°N and south of 80°S
+//
+// Each pole has its own zone, a stereographic projection centered on
+// the pole with the prime meridian pointing to grid north (south pole)
+// or grid south (north pole). The pole itself is at easting 2,000 km,
+// northing 2,000 km. Each zone is split at the prime meridian into a
+// western and an eastern half, lettered A and B in the south and Y and
+// Z in the north.
+//
+// Reference for UPS can be found here: https://en.wikipedia.org/wiki/Universal_polar_stereographic_coordinate_system
+package ups
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale factor at the poles
+const k0 = 0.994
+
+// Easting and northing of the poles, in meters
+const falseOrigin = 2000000
+
+// Latitudes UPS covers, overlapping UTM by half a degree
+const (
+	minNorth = 83.5
+	maxSouth = -79.5
+)
+
+// Coordinate contains coordinates in the Universal Polar Stereographic
+// coordinate system
+type Coordinate struct {
+	Easting    float64
+	Northing   float64
+	ZoneLetter string // A or B around the south pole, Y or Z around the north pole
+
+	// Reference ellipsoid the grid is projected from, nil meaning WGS84
+	Ellipsoid *latlong.Ellipsoid
+
+	Altitude latlong.Length // Height above the reference ellipsoid
+}
+
+// Projection constants for an ellipsoid, nil meaning WGS84: its
+// eccentricity, and the distance on the grid from the pole per unit of
+// t (see ToCoordinateOn)
+func constants(ell *latlong.Ellipsoid) (e, scale float64) {
+	if ell == nil {
+		ell = latlong.WGS84
+	}
+	e = math.Sqrt(ell.EccentricitySquared())
+	scale = 2 * ell.SemiMajorAxis() * k0 / math.Sqrt(math.Pow(1+e, 1+e)*math.Pow(1-e, 1-e))
+	return
+}
+
+// Whether a zone letter is one of the northern zones
+func northern(letter string) (bool, error) {
+	switch strings.ToUpper(letter) {
+	case "A", "B":
+		return false, nil
+	case "Y", "Z":
+		return true, nil
+	}
+	return false, errors.New(fmt.Sprintf("UPS zone letter '%s' is not A, B, Y or Z", letter))
+}
+
+// ToLatLong converts Universal Polar Stereographic (UPS) coordinates
+// to a latitude and longitude on the coordinate's reference ellipsoid
+func (c *Coordinate) ToLatLong() (latlong.Coordinate, error) {
+	north, err := northern(c.ZoneLetter)
+	if err != nil {
+		return latlong.Coordinate{}, err
+	}
+	if !(0 <= c.Easting && c.Easting <= 2*falseOrigin && 0 <= c.Northing && c.Northing <= 2*falseOrigin) {
+		return latlong.Coordinate{}, errors.New(fmt.Sprintf("UPS easting and northing must be between 0 m and %d m", 2*falseOrigin))
+	}
+	// The western zones, A and Y, end at the prime meridian, which is
+	// the line of easting through the pole
+	if west := c.Easting < falseOrigin; west != (strings.ToUpper(c.ZoneLetter) == "A" || strings.ToUpper(c.ZoneLetter) == "Y") {
+		return latlong.Coordinate{}, errors.New(fmt.Sprintf("UPS easting %v is not in zone %s", c.Easting, c.ZoneLetter))
+	}
+
+	e, scale := constants(c.Ellipsoid)
+	x := c.Easting - falseOrigin
+	y := c.Northing - falseOrigin
+	if north {
+		y = -y
+	}
+
+	// Solve for the latitude by fixed point iteration from the
+	// conformal latitude. Each round gains a factor of about e² in
+	// accuracy, so five are plenty.
+	t := math.Hypot(x, y) / scale
+	lat := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 5; i++ {
+		eSin := e * math.Sin(lat)
+		lat = math.Pi/2 - 2*math.Atan(t*math.Pow((1-eSin)/(1+eSin), e/2))
+	}
+	lon := math.Atan2(x, y)
+
+	if !north {
+		lat = -lat
+	}
+	return latlong.Coordinate{
+		Latitude:  deg(lat),
+		Longitude: deg(lon),
+		Altitude:  c.Altitude,
+	}, nil
+}
+
+// ToCoordinate converts a LatLonger to Universal Polar Stereographic
+// coordinates on WGS84
+func ToCoordinate(point latlong.LatLonger) (Coordinate, error) {
+	return ToCoordinateOn(point, latlong.WGS84)
+}
+
+// ToCoordinateOn converts a LatLonger to Universal Polar Stereographic
+// coordinates projected from the given reference ellipsoid. The point
+// must be north of 83.5°N or south of 79.5°S.
+func ToCoordinateOn(point latlong.LatLonger, ell *latlong.Ellipsoid) (Coordinate, error) {
+	lat, lon := point.Lat(), point.Lon()
+	if !(minNorth <= lat && lat <= 90 || -90 <= lat && lat <= maxSouth) {
+		return Coordinate{}, errors.New(fmt.Sprintf("Latitude %v is not north of %v or south of %v", lat, minNorth, maxSouth))
+	}
+	if !(-180 <= lon && lon <= 180) {
+		return Coordinate{}, errors.New(fmt.Sprintf("Longitude %v is out of range [-180, 180]", lon))
+	}
+
+	var coord Coordinate
+	if ell != latlong.WGS84 {
+		coord.Ellipsoid = ell
+	}
+	coord.Altitude = latlong.Altitude(point)
+
+	// Distance on the grid from the pole is scale * t, where t shrinks
+	// to 0 at the pole
+	north := lat > 0
+	e, scale := constants(ell)
+	phi := rad(math.Abs(lat))
+	eSin := e * math.Sin(phi)
+	t := math.Tan(math.Pi/4-phi/2) / math.Pow((1-eSin)/(1+eSin), e/2)
+	sinLon, cosLon := math.Sincos(rad(lon))
+
+	coord.Easting = falseOrigin + scale*t*sinLon
+	if north {
+		coord.Northing = falseOrigin - scale*t*cosLon
+	} else {
+		coord.Northing = falseOrigin + scale*t*cosLon
+	}
+
+	// Zones split at the prime meridian, which is a line of easting
+	west := coord.Easting < falseOrigin
+	switch {
+	case north && west:
+		coord.ZoneLetter = "Y"
+	case north:
+		coord.ZoneLetter = "Z"
+	case west:
+		coord.ZoneLetter = "A"
+	default:
+		coord.ZoneLetter = "B"
+	}
+	return coord, nil
+}
+
+// Convert angle in radians to angle in degrees
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Convert angle in degrees to angle in radians
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+
+func (c *Coordinate) UnmarshalJSON(b []byte) error {
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+
+	// Check number of fields in JSON object. The Ellipsoid and Altitude
+	// fields are optional.
+	_, hasEllipsoid := obj["Ellipsoid"]
+	_, hasAltitude := obj["Altitude"]
+	optional := 0
+	if hasEllipsoid {
+		optional++
+	}
+	if hasAltitude {
+		optional++
+	}
+	if len(obj) > 3+optional {
+		return errors.New(fmt.Sprintf("Too many fields for ups.Coordinate"))
+	}
+	if len(obj) < 3 {
+		return errors.New(fmt.Sprintf("Not enough fields for ups.Coordinate"))
+	}
+
+	// Check Easting
+	if _, ok := obj["Easting"]; !ok {
+		return errors.New("Missing field 'Easting'")
+	}
+	if _, ok := obj["Easting"].(float64); !ok {
+		return errors.New("Wrong type for field 'Easting'")
+	}
+
+	// Check Northing
+	if _, ok := obj["Northing"]; !ok {
+		return errors.New("Missing field 'Northing'")
+	}
+	if _, ok := obj["Northing"].(float64); !ok {
+		return errors.New("Wrong type for field 'Northing'")
+	}
+
+	// Check ZoneLetter
+	if _, ok := obj["ZoneLetter"]; !ok {
+		return errors.New("Missing field 'ZoneLetter'")
+	}
+	if _, ok := obj["ZoneLetter"].(string); !ok {
+		return errors.New("Wrong type for field 'ZoneLetter'")
+	}
+	if _, err := northern(obj["ZoneLetter"].(string)); err != nil {
+		return err
+	}
+
+	// Check Ellipsoid
+	var ell *latlong.Ellipsoid
+	if hasEllipsoid {
+		name, ok := obj["Ellipsoid"].(string)
+		if !ok {
+			return errors.New("Wrong type for field 'Ellipsoid'")
+		}
+		var err error
+		if ell, err = latlong.LookupEllipsoid(name); err != nil {
+			return err
+		}
+	}
+
+	// Check Altitude
+	var altitude float64
+	if hasAltitude {
+		var ok bool
+		if altitude, ok = obj["Altitude"].(float64); !ok {
+			return errors.New("Wrong type for field 'Altitude'")
+		}
+	}
+
+	// Check that the point is in its zone
+	parsed := Coordinate{
+		Easting:    obj["Easting"].(float64),
+		Northing:   obj["Northing"].(float64),
+		ZoneLetter: obj["ZoneLetter"].(string),
+		Ellipsoid:  ell,
+		Altitude:   latlong.Meters(altitude),
+	}
+	if _, err := parsed.ToLatLong(); err != nil {
+		return err
+	}
+
+	// All clear
+	*c = parsed
+	return nil
+}
+
+// jsonCoordinate is the form of Coordinate that UnmarshalJSON accepts
+type jsonCoordinate struct {
+	Easting    float64
+	Northing   float64
+	ZoneLetter string
+	Ellipsoid  string  `json:",omitempty"`
+	Altitude   float64 `json:",omitempty"`
+}
+
+// MarshalJSON encodes the coordinate as an object with the fields
+// UnmarshalJSON requires, naming its ellipsoid if it is not WGS84 and
+// giving its altitude in meters if it is not zero
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	j := jsonCoordinate{c.Easting, c.Northing, c.ZoneLetter, "", c.Altitude.Meters()}
+	if c.Ellipsoid != nil && c.Ellipsoid != latlong.WGS84 {
+		j.Ellipsoid = c.Ellipsoid.Name()
+	}
+	return json.Marshal(j)
+}
+
+// MarshalText encodes the coordinate as its zone, easting and northing,
+// e.g. "Z 2000000 1666855.51", followed by its altitude in meters if it
+// is not zero and the name of its ellipsoid if it is not WGS84
+func (c Coordinate) MarshalText() ([]byte, error) {
+	if math.IsNaN(c.Easting+c.Northing) || math.IsInf(c.Easting+c.Northing, 0) {
+		return nil, errors.New("Cannot marshal a ups.Coordinate that is not finite")
+	}
+	b := []byte(c.ZoneLetter + " ")
+	b = strconv.AppendFloat(b, c.Easting, 'f', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, c.Northing, 'f', -1, 64)
+	if c.Altitude != 0 {
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, c.Altitude.Meters(), 'f', -1, 64)
+	}
+	if c.Ellipsoid != nil && c.Ellipsoid != latlong.WGS84 {
+		b = append(b, ' ')
+		b = append(b, c.Ellipsoid.Name()...)
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes the format written by MarshalText
+func (c *Coordinate) UnmarshalText(b []byte) error {
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 || len(fields) > 5 {
+		return errors.New("ups.Coordinate needs a zone, an easting and a northing")
+	}
+
+	if _, err := northern(fields[0]); err != nil {
+		return err
+	}
+	easting, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Bad easting '%s' for ups.Coordinate", fields[1]))
+	}
+	northing, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Bad northing '%s' for ups.Coordinate", fields[2]))
+	}
+	// An altitude may follow, then an ellipsoid
+	var altitude float64
+	rest := fields[3:]
+	if len(rest) > 0 {
+		if a, err := strconv.ParseFloat(rest[0], 64); err == nil {
+			altitude = a
+			rest = rest[1:]
+		}
+	}
+	var ell *latlong.Ellipsoid
+	if len(rest) > 1 {
+		return errors.New(fmt.Sprintf("Bad altitude '%s' for ups.Coordinate", rest[0]))
+	}
+	if len(rest) == 1 {
+		if ell, err = latlong.LookupEllipsoid(rest[0]); err != nil {
+			return err
+		}
+	}
+
+	parsed := Coordinate{
+		Easting:    easting,
+		Northing:   northing,
+		ZoneLetter: fields[0],
+		Ellipsoid:  ell,
+		Altitude:   latlong.Meters(altitude),
+	}
+	if _, err := parsed.ToLatLong(); err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func (c Coordinate) Lat() float64 {
+	point, err := c.ToLatLong()
+	if err == nil {
+		return point.Latitude
+	}
+	return 0
+}
+
+func (c Coordinate) Lon() float64 {
+	point, err := c.ToLatLong()
+	if err == nil {
+		return point.Longitude
+	}
+	return 0
+}
+
+func (c Coordinate) Alt() latlong.Length {
+	return c.Altitude
+}
@@ -0,0 +1,168 @@
+package ups
+
+import (
+	"encoding/json"
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	// Maximum difference between floating point values. The polar
+	// stereographic projection has closed forms, so this is tighter
+	// than for UTM.
+	closeEnough = 1e-9
+)
+
+// Random latitude within UPS, in either polar region
+func randLatitude() float64 {
+	if rand.Intn(2) == 0 {
+		return minNorth + rand.Float64()*(90-minNorth)
+	}
+	return -90 + rand.Float64()*(maxSouth+90)
+}
+
+// Check that the poles are at the false origin, that the prime meridian
+// runs along grid north and south, and the scale factor at the pole.
+func TestPoles(t *testing.T) {
+	for _, lat := range []float64{90, -90} {
+		c, err := ToCoordinate(latlong.Coordinate{Latitude: lat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Easting != falseOrigin || c.Northing != falseOrigin {
+			t.Errorf("Pole at %v is at %v, %v", lat, c.Easting, c.Northing)
+		}
+	}
+
+	near := latlong.Coordinate{Latitude: 89.99, Longitude: 0}
+	c, err := ToCoordinate(near)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ZoneLetter != "Z" || math.Abs(c.Easting-falseOrigin) > closeEnough {
+		t.Errorf("%v is at %v, which is not grid south of the pole", near, c)
+	}
+	k := (falseOrigin - c.Northing) / latlong.GeodesicDistance(near, latlong.Coordinate{Latitude: 90}).Meters()
+	if math.Abs(k-k0) > 1e-6 {
+		t.Errorf("Scale factor near the pole is %f, wanted %f", k, k0)
+	}
+
+	c, err = ToCoordinate(latlong.Coordinate{Latitude: -85, Longitude: -90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ZoneLetter != "A" || math.Abs(c.Northing-falseOrigin) > 1e-6 || c.Easting >= falseOrigin {
+		t.Errorf("85°S 90°W is at %v, which is not grid west of the pole", c)
+	}
+}
+
+// Generate 100,000 random lat/long coordinates in each polar region on
+// each of the common reference ellipsoids, convert them to UPS, convert
+// them back, and assert that we got something close enough to the
+// original.
+func TestRandPoints(t *testing.T) {
+	ellipsoids := []*latlong.Ellipsoid{latlong.WGS84, latlong.GRS80, latlong.Clarke1866}
+	for i := 0; i < 100000; i++ {
+		ell := ellipsoids[i%len(ellipsoids)]
+		want := latlong.Coordinate{
+			Latitude:  randLatitude(),
+			Longitude: -180 + rand.Float64()*360,
+		}
+
+		coord, err := ToCoordinateOn(want, ell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := coord.ToLatLong()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+			t.Fatalf("%s: difference in latitude (%g) outside of acceptable range (%g)", ell, d, closeEnough)
+		}
+		// Longitude is meaningless at the pole, and loses precision
+		// near it
+		if d := math.Abs(want.Longitude-got.Longitude) * math.Cos(want.Latitude*math.Pi/180); d > closeEnough {
+			t.Fatalf("%s: difference in longitude (%g) outside of acceptable range (%g)", ell, d, closeEnough)
+		}
+	}
+}
+
+// Check that latitudes outside the polar regions are rejected.
+func TestRange(t *testing.T) {
+	for _, lat := range []float64{0, 83, -79, 90.5, math.NaN()} {
+		if c, err := ToCoordinate(latlong.Coordinate{Latitude: lat}); err == nil {
+			t.Errorf("Latitude %v converted to %v", lat, c)
+		}
+	}
+	for _, letter := range []string{"", "C", "X", "N"} {
+		c := Coordinate{Easting: falseOrigin, Northing: falseOrigin, ZoneLetter: letter}
+		if point, err := c.ToLatLong(); err == nil {
+			t.Errorf("Zone letter '%s' converted to %v", letter, point)
+		}
+	}
+
+	// Off the grid, or on the wrong side of the prime meridian
+	bad := []string{
+		`{"Easting": 5000000, "Northing": 2000000, "ZoneLetter": "Z"}`,
+		`{"Easting": 2000000, "Northing": -1, "ZoneLetter": "B"}`,
+		`{"Easting": 2500000, "Northing": 2000000, "ZoneLetter": "Y"}`,
+		`{"Easting": 1500000, "Northing": 2000000, "ZoneLetter": "b"}`,
+		`{"Easting": 2000000, "Northing": 2000000, "ZoneLetter": "A"}`,
+	}
+	for _, s := range bad {
+		var c Coordinate
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			t.Errorf("Unmarshaling %s succeeded", s)
+		}
+	}
+	for _, s := range []string{"Z 5000000 2000000", "Y 2500000 2000000"} {
+		var c Coordinate
+		if err := c.UnmarshalText([]byte(s)); err == nil {
+			t.Errorf("Unmarshaling %s succeeded", s)
+		}
+	}
+}
+
+// Marshal random UPS coordinates, half of them with altitudes, to JSON
+// and text and assert that they decode to exactly the original.
+func TestRandMarshal(t *testing.T) {
+	ellipsoids := []*latlong.Ellipsoid{nil, latlong.GRS80, latlong.Clarke1866}
+	for i := 0; i < 10000; i++ {
+		want, err := ToCoordinateOn(&latlong.Coordinate{
+			Latitude:  randLatitude(),
+			Longitude: -180 + rand.Float64()*360,
+			Altitude:  latlong.Meters(float64(i%2) * (rand.Float64()*5000 - 500)),
+		}, ellipsoids[i%len(ellipsoids)])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Coordinate
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", b, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, b, got)
+		}
+
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = Coordinate{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", text, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, text, got)
+		}
+	}
+}
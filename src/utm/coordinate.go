@@ -34,36 +34,78 @@ import (
 	"fmt"
 	"latlong"
 	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"unicode"
+	"ups"
 )
 
-const (
-	k0 float64 = 0.9996
-	e  float64 = 0.00669438
-	r          = 6378137
-)
+const k0 float64 = 0.9996
+
+// ellipsoid holds the series constants of the transverse Mercator
+// projection for one reference ellipsoid
+type ellipsoid struct {
+	r, e, e2, e3, e_p2 float64
+
+	_e, _e2, _e3, _e4, _e5 float64
+
+	m1, m2, m3, m4 float64
+
+	p2, p3, p4, p5 float64
+}
 
-var e2 = e * e
-var e3 = e2 * e
-var e_p2 = e / (1.0 - e)
+var wgs84 = newEllipsoid(latlong.WGS84)
 
-var sqrt_e = math.Sqrt(1 - e)
+// Projection constants of the other ellipsoids seen so far
+var (
+	ellipsoidsMu sync.Mutex
+	ellipsoids   = map[*latlong.Ellipsoid]*ellipsoid{}
+)
 
-var _e = (1 - sqrt_e) / (1 + sqrt_e)
-var _e2 = _e * _e
-var _e3 = _e2 * _e
-var _e4 = _e3 * _e
-var _e5 = _e4 * _e
+func newEllipsoid(ell *latlong.Ellipsoid) *ellipsoid {
+	p := &ellipsoid{r: ell.SemiMajorAxis(), e: ell.EccentricitySquared()}
+	e := p.e
+	p.e2 = e * e
+	p.e3 = p.e2 * e
+	p.e_p2 = e / (1.0 - e)
+
+	sqrt_e := math.Sqrt(1 - e)
+
+	p._e = (1 - sqrt_e) / (1 + sqrt_e)
+	p._e2 = p._e * p._e
+	p._e3 = p._e2 * p._e
+	p._e4 = p._e3 * p._e
+	p._e5 = p._e4 * p._e
+
+	p.m1 = (1 - e/4 - 3*p.e2/64 - 5*p.e3/256)
+	p.m2 = (3*e/8 + 3*p.e2/32 + 45*p.e3/1024)
+	p.m3 = (15*p.e2/256 + 45*p.e3/1024)
+	p.m4 = (35 * p.e3 / 3072)
+
+	p.p2 = (3./2*p._e - 27./32*p._e3 + 269./512*p._e5)
+	p.p3 = (21./16*p._e2 - 55./32*p._e4)
+	p.p4 = (151./96*p._e3 - 417./128*p._e5)
+	p.p5 = (1097. / 512 * p._e4)
+	return p
+}
 
-var m1 = (1 - e/4 - 3*e2/64 - 5*e3/256)
-var m2 = (3*e/8 + 3*e2/32 + 45*e3/1024)
-var m3 = (15*e2/256 + 45*e3/1024)
-var m4 = (35 * e3 / 3072)
+// Find the projection constants for an ellipsoid, nil meaning WGS84,
+// building them the first time the ellipsoid is seen
+func ellipsoidFor(ell *latlong.Ellipsoid) *ellipsoid {
+	if ell == nil || ell == latlong.WGS84 {
+		return wgs84
+	}
 
-var p2 = (3./2*_e - 27./32*_e3 + 269./512*_e5)
-var p3 = (21./16*_e2 - 55./32*_e4)
-var p4 = (151./96*_e3 - 417./128*_e5)
-var p5 = (1097. / 512 * _e4)
+	ellipsoidsMu.Lock()
+	defer ellipsoidsMu.Unlock()
+	p, ok := ellipsoids[ell]
+	if !ok {
+		p = newEllipsoid(ell)
+		ellipsoids[ell] = p
+	}
+	return p
+}
 
 type zone_letter struct {
 	zone   int
@@ -76,7 +118,6 @@ func rad(d float64) float64 { return d * x }
 func deg(r float64) float64 { return r / x }
 
 var zone_letters = []zone_letter{
-	{84, " "},
 	{72, "X"},
 	{64, "W"},
 	{56, "V"},
@@ -100,16 +141,31 @@ var zone_letters = []zone_letter{
 }
 
 // Coordinate contains coordinates in the Universal Transverse
-// Mercator coordinate system
+// Mercator coordinate system. Beyond UTM's reach, north of 84°N and
+// south of 80°S, it holds Universal Polar Stereographic coordinates
+// instead (see package ups), with ZoneNumber 0 and ZoneLetter A, B, Y
+// or Z.
 type Coordinate struct {
 	Easting    float64
 	Northing   float64
 	ZoneNumber int
 	ZoneLetter string
+
+	// Reference ellipsoid the grid is projected from, nil meaning WGS84.
+	// Legacy NAD27 sheets use latlong.Clarke1866.
+	Ellipsoid *latlong.Ellipsoid
+
+	Altitude latlong.Length // Height above the reference ellipsoid
 }
 
 // ToLatLong converts Universal Transverse Mercator (UTM) coordinates to a latitude and longitude
+// on the coordinate's reference ellipsoid
 func (coordinate *Coordinate) ToLatLong() (latlong.Coordinate, error) {
+	if coordinate.ZoneNumber == 0 {
+		polar := coordinate.ups()
+		return polar.ToLatLong()
+	}
+
 	zoneLetterExist := !(coordinate.ZoneLetter == "")
 
 	if !zoneLetterExist {
@@ -135,6 +191,8 @@ func (coordinate *Coordinate) ToLatLong() (latlong.Coordinate, error) {
 		return latlong.Coordinate{}, err
 	}
 
+	p := ellipsoidFor(coordinate.Ellipsoid)
+
 	northernValue := (zoneLetter >= 'N')
 	x := coordinate.Easting - 500000
 	y := coordinate.Northing
@@ -144,13 +202,13 @@ func (coordinate *Coordinate) ToLatLong() (latlong.Coordinate, error) {
 	}
 
 	m := y / k0
-	mu := m / (r * m1)
+	mu := m / (p.r * p.m1)
 
 	p_rad := (mu +
-		p2*math.Sin(2*mu) +
-		p3*math.Sin(4*mu) +
-		p4*math.Sin(6*mu) +
-		p5*math.Sin(8*mu))
+		p.p2*math.Sin(2*mu) +
+		p.p3*math.Sin(4*mu) +
+		p.p4*math.Sin(6*mu) +
+		p.p5*math.Sin(8*mu))
 
 	p_sin := math.Sin(p_rad)
 	p_sin2 := p_sin * p_sin
@@ -161,13 +219,13 @@ func (coordinate *Coordinate) ToLatLong() (latlong.Coordinate, error) {
 	p_tan2 := p_tan * p_tan
 	p_tan4 := p_tan2 * p_tan2
 
-	ep_sin := 1 - e*p_sin2
-	ep_sin_sqrt := math.Sqrt(1 - e*p_sin2)
+	ep_sin := 1 - p.e*p_sin2
+	ep_sin_sqrt := math.Sqrt(1 - p.e*p_sin2)
 
-	n := r / ep_sin_sqrt
-	rad := (1 - e) / ep_sin
+	n := p.r / ep_sin_sqrt
+	rad := (1 - p.e) / ep_sin
 
-	c := _e * p_cos * p_cos
+	c := p._e * p_cos * p_cos
 	c2 := c * c
 
 	d := x / (n * k0)
@@ -179,29 +237,69 @@ func (coordinate *Coordinate) ToLatLong() (latlong.Coordinate, error) {
 
 	latitude := (p_rad - (p_tan/rad)*
 		(d2/2-
-			d4/24*(5+3*p_tan2+10*c-4*c2-9*e_p2)) +
-		d6/720*(61+90*p_tan2+298*c+45*p_tan4-252*e_p2-3*c2))
+			d4/24*(5+3*p_tan2+10*c-4*c2-9*p.e_p2)) +
+		d6/720*(61+90*p_tan2+298*c+45*p_tan4-252*p.e_p2-3*c2))
 
 	longitude := (d -
 		d3/6*(1+2*p_tan2+c) +
-		d5/120*(5-2*c+28*p_tan2-3*c2+8*e_p2+24*p_tan4)) / p_cos
+		d5/120*(5-2*c+28*p_tan2-3*c2+8*p.e_p2+24*p_tan4)) / p_cos
 
-	return latlong.Coordinate{deg(latitude), deg(longitude) + float64(zone_number_to_central_longitude(coordinate.ZoneNumber))}, nil
+	return latlong.Coordinate{
+		Latitude:  deg(latitude),
+		Longitude: deg(longitude) + float64(zone_number_to_central_longitude(coordinate.ZoneNumber)),
+		Altitude:  coordinate.Altitude,
+	}, nil
 
 }
 
-// ToCoordinate converts a LatLonger to Universal Transverse Mercator coordinates
+// ToCoordinate converts a LatLonger to Universal Transverse Mercator coordinates on WGS84,
+// or to Universal Polar Stereographic coordinates near the poles
 func ToCoordinate(point latlong.LatLonger) (coord Coordinate, err error) {
-	if !(-80.0 <= point.Lat() && point.Lat() <= 84.0) {
-		err = errors.New("latitude out of range (must be between 80 deg S and 84 deg N)")
+	return ToCoordinateOn(point, latlong.WGS84)
+}
+
+// ToCoordinateOn converts a LatLonger to Universal Transverse Mercator coordinates
+// projected from the given reference ellipsoid, or to Universal Polar Stereographic
+// coordinates near the poles
+func ToCoordinateOn(point latlong.LatLonger, ell *latlong.Ellipsoid) (coord Coordinate, err error) {
+	if !(-90.0 <= point.Lat() && point.Lat() <= 90.0) {
+		err = errors.New("latitude out of range (must be between 90 deg S and 90 deg N)")
 		return
 	}
 	if !(-180.0 <= point.Lon() && point.Lon() <= 180.0) {
 		err = errors.New("longitude out of range (must be between 180 deg W and 180 deg E)")
 		return
 	}
+	if !(-80.0 <= point.Lat() && point.Lat() <= 84.0) {
+		var polar ups.Coordinate
+		if polar, err = ups.ToCoordinateOn(point, ell); err != nil {
+			return
+		}
+		coord = FromUPS(polar)
+		return
+	}
+
+	p := ellipsoidFor(ell)
+	if ell != latlong.WGS84 {
+		coord.Ellipsoid = ell
+	}
+	coord.Altitude = latlong.Altitude(point)
+
+	coord.ZoneNumber = latlon_to_zone_number(point.Lat(), point.Lon())
+	coord.ZoneLetter = latitude_to_zone_letter(point.Lat())
+	coord.Easting, coord.Northing = project(point.Lat(), point.Lon(), coord.ZoneNumber, p)
+
+	if point.Lat() < 0 {
+		coord.Northing += 10000000
+	}
+
+	return
+}
 
-	lat_rad := rad(point.Lat())
+// project finds the easting and northing of a latitude and longitude in
+// the given zone, without the false northing of the southern hemisphere
+func project(latitude, longitude float64, zone_number int, p *ellipsoid) (easting, northing float64) {
+	lat_rad := rad(latitude)
 	lat_sin := math.Sin(lat_rad)
 	lat_cos := math.Cos(lat_rad)
 
@@ -209,16 +307,12 @@ func ToCoordinate(point latlong.LatLonger) (coord Coordinate, err error) {
 	lat_tan2 := lat_tan * lat_tan
 	lat_tan4 := lat_tan2 * lat_tan2
 
-	coord.ZoneNumber = latlon_to_zone_number(point.Lat(), point.Lon())
-
-	coord.ZoneLetter = latitude_to_zone_letter(point.Lat())
-
-	lon_rad := rad(point.Lon())
-	central_lon := zone_number_to_central_longitude(coord.ZoneNumber)
+	lon_rad := rad(longitude)
+	central_lon := zone_number_to_central_longitude(zone_number)
 	central_lon_rad := rad(float64(central_lon))
 
-	n := r / math.Sqrt(1-e*lat_sin*lat_sin)
-	c := e_p2 * lat_cos * lat_cos
+	n := p.r / math.Sqrt(1-p.e*lat_sin*lat_sin)
+	c := p.e_p2 * lat_cos * lat_cos
 
 	a := lat_cos * (lon_rad - central_lon_rad)
 	a2 := a * a
@@ -226,22 +320,40 @@ func ToCoordinate(point latlong.LatLonger) (coord Coordinate, err error) {
 	a4 := a3 * a
 	a5 := a4 * a
 	a6 := a5 * a
-	m := r * (m1*lat_rad -
-		m2*math.Sin(2*lat_rad) +
-		m3*math.Sin(4*lat_rad) -
-		m4*math.Sin(6*lat_rad))
-	coord.Easting = k0*n*(a+
+	m := p.r * (p.m1*lat_rad -
+		p.m2*math.Sin(2*lat_rad) +
+		p.m3*math.Sin(4*lat_rad) -
+		p.m4*math.Sin(6*lat_rad))
+	easting = k0*n*(a+
 		a3/6*(1-lat_tan2+c)+
-		a5/120*(5-18*lat_tan2+lat_tan4+72*c-58*e_p2)) + 500000
-	coord.Northing = k0 * (m + n*lat_tan*(a2/2+
+		a5/120*(5-18*lat_tan2+lat_tan4+72*c-58*p.e_p2)) + 500000
+	northing = k0 * (m + n*lat_tan*(a2/2+
 		a4/24*(5-lat_tan2+9*c+4*c*c)+
-		a6/720*(61-58*lat_tan2+lat_tan4+600*c-330*e_p2)))
+		a6/720*(61-58*lat_tan2+lat_tan4+600*c-330*p.e_p2)))
+	return
+}
 
-	if point.Lat() < 0 {
-		coord.Northing += 10000000
+// FromUPS gives the Universal Polar Stereographic coordinate as a
+// Coordinate in zone 0
+func FromUPS(polar ups.Coordinate) Coordinate {
+	return Coordinate{
+		Easting:    polar.Easting,
+		Northing:   polar.Northing,
+		ZoneLetter: polar.ZoneLetter,
+		Ellipsoid:  polar.Ellipsoid,
+		Altitude:   polar.Altitude,
 	}
+}
 
-	return
+// The Universal Polar Stereographic coordinate of a zone 0 Coordinate
+func (c *Coordinate) ups() ups.Coordinate {
+	return ups.Coordinate{
+		Easting:    c.Easting,
+		Northing:   c.Northing,
+		ZoneLetter: c.ZoneLetter,
+		Ellipsoid:  c.Ellipsoid,
+		Altitude:   c.Altitude,
+	}
 }
 
 func latitude_to_zone_letter(latitude float64) string {
@@ -283,8 +395,18 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	// Check number of fields in JSON object
-	if len(obj) > 4 {
+	// Check number of fields in JSON object. The Ellipsoid and Altitude
+	// fields are optional.
+	_, hasEllipsoid := obj["Ellipsoid"]
+	_, hasAltitude := obj["Altitude"]
+	optional := 0
+	if hasEllipsoid {
+		optional++
+	}
+	if hasAltitude {
+		optional++
+	}
+	if len(obj) > 4+optional {
 		return errors.New(fmt.Sprintf("Too many fields for utm.Coordinate"))
 	}
 	if len(obj) < 4 {
@@ -311,10 +433,9 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 	if _, ok := obj["ZoneNumber"]; !ok {
 		return errors.New("Missing field 'ZoneNumber'")
 	}
-	if i, ok := obj["ZoneNumber"].(int); !ok {
-		if i-int(i) != 0 {
-			return errors.New("Wrong type for field 'ZoneNumber'")
-		}
+	// JSON numbers always decode as float64, so insist on a whole one
+	if n, ok := obj["ZoneNumber"].(float64); !ok || n != math.Trunc(n) {
+		return errors.New("Wrong type for field 'ZoneNumber'")
 	}
 
 	// Check ZoneLetter
@@ -325,16 +446,156 @@ func (c *Coordinate) UnmarshalJSON(b []byte) error {
 		return errors.New("Wrong type for field 'ZoneLetter'")
 	}
 
+	// Check Ellipsoid
+	var ell *latlong.Ellipsoid
+	if hasEllipsoid {
+		name, ok := obj["Ellipsoid"].(string)
+		if !ok {
+			return errors.New("Wrong type for field 'Ellipsoid'")
+		}
+		var err error
+		if ell, err = latlong.LookupEllipsoid(name); err != nil {
+			return err
+		}
+	}
+
+	// Check Altitude
+	var altitude float64
+	if hasAltitude {
+		var ok bool
+		if altitude, ok = obj["Altitude"].(float64); !ok {
+			return errors.New("Wrong type for field 'Altitude'")
+		}
+	}
+
 	// All clear
 	c.Easting = obj["Easting"].(float64)
 	c.Northing = obj["Northing"].(float64)
-	if _, ok := obj["ZoneNumber"].(int); !ok {
-		tmpZNum := obj["ZoneNumber"].(float64)
-		c.ZoneNumber = int(tmpZNum)
-	} else {
-		c.ZoneNumber = obj["ZoneNumber"].(int)
-	}
+	c.ZoneNumber = int(obj["ZoneNumber"].(float64))
 	c.ZoneLetter = obj["ZoneLetter"].(string)
+	c.Ellipsoid = ell
+	c.Altitude = latlong.Meters(altitude)
+	return nil
+}
+
+// jsonCoordinate is the form of Coordinate that UnmarshalJSON accepts
+type jsonCoordinate struct {
+	Easting    float64
+	Northing   float64
+	ZoneNumber int
+	ZoneLetter string
+	Ellipsoid  string  `json:",omitempty"`
+	Altitude   float64 `json:",omitempty"`
+}
+
+// ellipsoidName names the ellipsoid of a coordinate for the formats
+// UnmarshalJSON and UnmarshalText read, or gives "" for WGS84. Only
+// ellipsoids known to latlong.LookupEllipsoid can be read back, so
+// others, such as those made with latlong.NewEllipsoid, are an error.
+func ellipsoidName(ell *latlong.Ellipsoid) (string, error) {
+	if ell == nil || ell == latlong.WGS84 {
+		return "", nil
+	}
+	if known, err := latlong.LookupEllipsoid(ell.Name()); err != nil || known != ell {
+		return "", errors.New(fmt.Sprintf("Cannot marshal a utm.Coordinate on unregistered ellipsoid '%s'", ell.Name()))
+	}
+	return ell.Name(), nil
+}
+
+// MarshalJSON encodes the coordinate as an object with the fields
+// UnmarshalJSON requires, naming its ellipsoid if it is not WGS84 and
+// giving its altitude in meters if it is not zero
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	name, err := ellipsoidName(c.Ellipsoid)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonCoordinate{c.Easting, c.Northing, c.ZoneNumber, c.ZoneLetter, name, c.Altitude.Meters()})
+}
+
+// MarshalText encodes the coordinate as its zone, easting and northing,
+// e.g. "31T 500000 4649776.22", followed by its altitude in meters if
+// it is not zero and the name of its ellipsoid if it is not WGS84. The
+// polar zones are written as just their letter, e.g. "Z 2000000 2000000".
+func (c Coordinate) MarshalText() ([]byte, error) {
+	if math.IsNaN(c.Easting+c.Northing) || math.IsInf(c.Easting+c.Northing, 0) {
+		return nil, errors.New("Cannot marshal a utm.Coordinate that is not finite")
+	}
+	name, err := ellipsoidName(c.Ellipsoid)
+	if err != nil {
+		return nil, err
+	}
+	zone := c.ZoneLetter
+	if c.ZoneNumber != 0 {
+		zone = strconv.Itoa(c.ZoneNumber) + zone
+	}
+	b := []byte(zone + " ")
+	b = strconv.AppendFloat(b, c.Easting, 'f', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, c.Northing, 'f', -1, 64)
+	if c.Altitude != 0 {
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, c.Altitude.Meters(), 'f', -1, 64)
+	}
+	if name != "" {
+		b = append(b, ' ')
+		b = append(b, name...)
+	}
+	return b, nil
+}
+
+// UnmarshalText decodes the format written by MarshalText
+func (c *Coordinate) UnmarshalText(b []byte) error {
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 || len(fields) > 5 {
+		return errors.New("utm.Coordinate needs a zone, an easting and a northing")
+	}
+
+	zone := fields[0]
+	if !unicode.IsLetter(rune(zone[len(zone)-1])) {
+		return errors.New(fmt.Sprintf("Bad zone '%s' for utm.Coordinate", zone))
+	}
+	// A lone letter is a polar zone
+	number := 0
+	var err error
+	if len(zone) > 1 {
+		if number, err = strconv.Atoi(zone[:len(zone)-1]); err != nil {
+			return errors.New(fmt.Sprintf("Bad zone '%s' for utm.Coordinate", zone))
+		}
+	}
+	easting, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Bad easting '%s' for utm.Coordinate", fields[1]))
+	}
+	northing, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Bad northing '%s' for utm.Coordinate", fields[2]))
+	}
+	// An altitude may follow, then an ellipsoid
+	var altitude float64
+	rest := fields[3:]
+	if len(rest) > 0 {
+		if a, err := strconv.ParseFloat(rest[0], 64); err == nil {
+			altitude = a
+			rest = rest[1:]
+		}
+	}
+	var ell *latlong.Ellipsoid
+	if len(rest) > 1 {
+		return errors.New(fmt.Sprintf("Bad altitude '%s' for utm.Coordinate", rest[0]))
+	}
+	if len(rest) == 1 {
+		if ell, err = latlong.LookupEllipsoid(rest[0]); err != nil {
+			return err
+		}
+	}
+
+	c.Easting = easting
+	c.Northing = northing
+	c.ZoneNumber = number
+	c.ZoneLetter = zone[len(zone)-1:]
+	c.Ellipsoid = ell
+	c.Altitude = latlong.Meters(altitude)
 	return nil
 }
 
@@ -353,3 +614,7 @@ func (c Coordinate) Lon() float64 {
 	}
 	return 0
 }
+
+func (c Coordinate) Alt() latlong.Length {
+	return c.Altitude
+}
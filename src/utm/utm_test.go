@@ -1,9 +1,12 @@
 package utm
 
 import (
+	"encoding/json"
+	"fmt"
 	"latlong"
 	"math"
 	"math/rand"
+	"projection"
 	"testing"
 )
 
@@ -52,3 +55,243 @@ func TestRandPoints(t *testing.T) {
 
 	}
 }
+
+// Round trip random lat/long coordinates through UTM grids projected
+// from each of the common reference ellipsoids.
+func TestRandPointsEllipsoids(t *testing.T) {
+	ellipsoids := []*latlong.Ellipsoid{
+		latlong.WGS84,
+		latlong.GRS80,
+		latlong.Clarke1866,
+		latlong.International1924,
+		latlong.Airy1830,
+	}
+
+	for _, ell := range ellipsoids {
+		for i := 0; i < 100000; i++ {
+			want := &latlong.Coordinate{
+				Latitude:  -79 + rand.Float64()*162,
+				Longitude: -180 + rand.Float64()*360,
+			}
+
+			coord, err := ToCoordinateOn(want, ell)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			got, err := coord.ToLatLong()
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+				t.Errorf("%s: difference in latitude (%f) outside of acceptable range (%f)", ell, d, closeEnough)
+				t.FailNow()
+			}
+			if d := math.Abs(want.Longitude - got.Longitude); d > closeEnough {
+				t.Errorf("%s: difference in longitude (%f) outside of acceptable range (%f)", ell, d, closeEnough)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// Marshal random UTM coordinates on each of the common reference
+// ellipsoids, half of them with altitudes, to JSON and text and assert
+// that they decode to exactly the original.
+func TestRandMarshal(t *testing.T) {
+	ellipsoids := []*latlong.Ellipsoid{nil, latlong.GRS80, latlong.Clarke1866}
+	for i := 0; i < 10000; i++ {
+		want, err := ToCoordinateOn(&latlong.Coordinate{
+			Latitude:  -79 + rand.Float64()*162,
+			Longitude: -180 + rand.Float64()*360,
+			Altitude:  latlong.Meters(float64(i%2) * (rand.Float64()*20000 - 500)),
+		}, ellipsoids[i%len(ellipsoids)])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Coordinate
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", b, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, b, got)
+		}
+
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = Coordinate{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("Unmarshaling %s failed: %s", text, err)
+		}
+		if got != want {
+			t.Errorf("%v marshaled as %s, which unmarshals as %v", want, text, got)
+		}
+	}
+}
+
+// Check that coordinates on ellipsoids LookupEllipsoid does not know,
+// which could not be read back, are not marshaled.
+func TestMarshalUnregistered(t *testing.T) {
+	ell, err := latlong.NewEllipsoid("Custom", 6378000, 298)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Not the registered ellipsoid, even under the same name
+	twin, err := latlong.NewEllipsoid("GRS80", 6378137, 298.257222101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*latlong.Ellipsoid{ell, twin} {
+		c, err := ToCoordinateOn(&latlong.Coordinate{Latitude: 45, Longitude: 3}, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := json.Marshal(c); err == nil {
+			t.Errorf("Coordinate on %s marshaled as %s", e, b)
+		}
+		if b, err := c.MarshalText(); err == nil {
+			t.Errorf("Coordinate on %s marshaled as %s", e, b)
+		}
+	}
+}
+
+// Check that zone numbers must be whole numbers.
+func TestZoneNumberType(t *testing.T) {
+	bad := []string{
+		`{"Easting":500000,"Northing":4649776.22,"ZoneNumber":"31","ZoneLetter":"T"}`,
+		`{"Easting":500000,"Northing":4649776.22,"ZoneNumber":31.5,"ZoneLetter":"T"}`,
+	}
+	for _, s := range bad {
+		var c Coordinate
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			t.Errorf("Unmarshaling %s succeeded", s)
+		}
+	}
+}
+
+// Round trip random lat/long coordinates from pole to pole, and check
+// that those beyond UTM's reach switch to UPS in zone 0.
+func TestRandPolar(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		want := &latlong.Coordinate{
+			Latitude:  -90 + rand.Float64()*180,
+			Longitude: -180 + rand.Float64()*360,
+		}
+
+		coord, err := ToCoordinate(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if polar := want.Latitude > 84 || want.Latitude < -80; polar != (coord.ZoneNumber == 0) {
+			t.Fatalf("%v converted to zone %d%s", want, coord.ZoneNumber, coord.ZoneLetter)
+		}
+
+		got, err := coord.ToLatLong()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+			t.Fatalf("Difference in latitude (%f) outside of acceptable range (%f)", d, closeEnough)
+		}
+		if d := math.Abs(want.Longitude-got.Longitude) * math.Cos(want.Latitude*math.Pi/180); d > closeEnough {
+			t.Fatalf("Difference in longitude (%f) outside of acceptable range (%f)", d, closeEnough)
+		}
+	}
+
+	// The top of band X is still UTM
+	if coord, err := ToCoordinate(&latlong.Coordinate{Latitude: 84, Longitude: 10}); err != nil || coord.ZoneLetter != "X" {
+		t.Errorf("84°N converted to %v, %v", coord, err)
+	}
+
+	// Polar zones are written as just their letter
+	var coord Coordinate
+	if err := coord.UnmarshalText([]byte("Z 2000000 2000000")); err != nil {
+		t.Fatal(err)
+	}
+	if point, err := coord.ToLatLong(); err != nil || point.Latitude != 90 {
+		t.Errorf("Z 2000000 2000000 converted to %v, %v", point, err)
+	}
+	if text, err := coord.MarshalText(); err != nil || string(text) != "Z 2000000 2000000" {
+		t.Errorf("Z 2000000 2000000 marshaled as %s, %v", text, err)
+	}
+}
+
+// Project random lat/long coordinates with the zones registered with
+// package projection, including the zones next to their own when they
+// are in range, and check that they agree with ToCoordinate and come
+// back to the original.
+func TestRandZones(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		want := latlong.Coordinate{
+			Latitude:  -79 + rand.Float64()*162,
+			Longitude: -180 + rand.Float64()*360,
+		}
+		coord, err := ToCoordinate(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Zones 1 and 60 are neighbours across the antimeridian
+		number := (coord.ZoneNumber+rand.Intn(3)+58)%60 + 1
+		name := fmt.Sprintf("EPSG:%d", 32700+number)
+		if want.Latitude >= 0 {
+			name = fmt.Sprintf("EPSG:%d", 32600+number)
+		}
+		p, err := projection.Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Points far from the zone next to their own are out of its range
+		point, err := p.Forward(want)
+		if err != nil && number != coord.ZoneNumber {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Projecting %v with %s failed: %s", want, p.Name(), err)
+		}
+		if number == coord.ZoneNumber && (point.Easting != coord.Easting || point.Northing != coord.Northing) {
+			t.Errorf("%s projected %v to %v, but ToCoordinate gave %v", p.Name(), want, point, coord)
+		}
+
+		got, err := p.Inverse(point)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The series lose precision outside the zone
+		tolerance := closeEnough
+		if number != coord.ZoneNumber {
+			tolerance *= 3
+		}
+		if d := math.Abs(want.Latitude - got.Latitude); d > tolerance {
+			t.Fatalf("Difference in latitude (%f) outside of acceptable range (%f)", d, tolerance)
+		}
+		if d := math.Abs(math.Remainder(want.Longitude-got.Longitude, 360)) * math.Cos(want.Latitude*math.Pi/180); d > tolerance {
+			t.Fatalf("Difference in longitude (%f) outside of acceptable range (%f)", d, tolerance)
+		}
+	}
+
+	// Zones keep to their hemisphere and to the longitudes near them
+	zone := Zone{Number: 31, North: true}
+	for _, point := range []latlong.Coordinate{{Latitude: -10, Longitude: 3}, {Latitude: 10, Longitude: 60}, {Latitude: 85, Longitude: 3}} {
+		if got, err := zone.Forward(point); err == nil {
+			t.Errorf("%s projected %v to %v", zone.Name(), point, got)
+		}
+	}
+
+	// UTM coordinates are read by projection.Unmarshal
+	l, err := projection.Unmarshal([]byte(`{"Easting":500000,"Northing":4649776.22,"ZoneNumber":31,"ZoneLetter":"T"}`))
+	if _, ok := l.(*Coordinate); err != nil || !ok {
+		t.Errorf("Unmarshaling a UTM coordinate gave %v, %v", l, err)
+	}
+}
@@ -0,0 +1,99 @@
+package utm
+
+import (
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+	"projection"
+)
+
+// Zone is the transverse Mercator projection of one UTM zone in one
+// hemisphere on WGS84, such as zone 33N (EPSG:32633). It implements
+// projection.Projection, and unlike ToCoordinate it keeps to its zone,
+// projecting points up to 6° from its central meridian, as the widest
+// zones do, though only to within a couple of meters outside the zone.
+type Zone struct {
+	Number int // 1 to 60
+	North  bool
+}
+
+// Register every zone, and Coordinate as a format of its own
+func init() {
+	for number := 1; number <= 60; number++ {
+		for _, north := range []bool{true, false} {
+			if err := projection.Register(Zone{number, north}); err != nil {
+				panic(err)
+			}
+		}
+	}
+	projection.RegisterFormat(func() latlong.LatLonger { return new(Coordinate) })
+}
+
+// Name of the zone, e.g. "UTM zone 33N"
+func (z Zone) Name() string {
+	if z.North {
+		return fmt.Sprintf("UTM zone %dN", z.Number)
+	}
+	return fmt.Sprintf("UTM zone %dS", z.Number)
+}
+
+// EPSG code of the zone on WGS84, 32601 to 32660 in the north and 32701
+// to 32760 in the south
+func (z Zone) EPSG() int {
+	if z.North {
+		return 32600 + z.Number
+	}
+	return 32700 + z.Number
+}
+
+// The letter of a latitude band in the hemisphere of the zone, which is
+// all ToLatLong needs of it
+func (z Zone) letter() string {
+	if z.North {
+		return "N"
+	}
+	return "M"
+}
+
+// Forward projects a point in the hemisphere of the zone, between 80°S
+// and 84°N, to its easting and northing in the zone
+func (z Zone) Forward(point latlong.LatLonger) (projection.Point, error) {
+	if !(1 <= z.Number && z.Number <= 60) {
+		return projection.Point{}, errors.New("zone number out of range (must be between 1 and 60)")
+	}
+	if !(-80.0 <= point.Lat() && point.Lat() <= 84.0) {
+		return projection.Point{}, errors.New("latitude out of range (must be between 80 deg S and 84 deg N)")
+	}
+	if z.North && point.Lat() < 0 || !z.North && point.Lat() > 0 {
+		return projection.Point{}, errors.New(fmt.Sprintf("latitude %g is not in the hemisphere of %s", point.Lat(), z.Name()))
+	}
+
+	// Take the longitude the short way round from the central meridian.
+	// The widest zones, around Norway and Svalbard, reach 6° from it.
+	central := float64(zone_number_to_central_longitude(z.Number))
+	offset := math.Remainder(point.Lon()-central, 360)
+	easting, northing := project(point.Lat(), central+offset, z.Number, wgs84)
+	if !z.North {
+		northing += 10000000
+	}
+	if math.Abs(offset) > 6 || !(100000 <= easting && easting < 1000000) {
+		return projection.Point{}, errors.New(fmt.Sprintf("longitude %g is too far from %s", point.Lon(), z.Name()))
+	}
+	return projection.Point{Easting: easting, Northing: northing}, nil
+}
+
+// Inverse finds the latitude and longitude of an easting and northing
+// in the zone
+func (z Zone) Inverse(p projection.Point) (latlong.Coordinate, error) {
+	if !(1 <= z.Number && z.Number <= 60) {
+		return latlong.Coordinate{}, errors.New("zone number out of range (must be between 1 and 60)")
+	}
+	c := Coordinate{Easting: p.Easting, Northing: p.Northing, ZoneNumber: z.Number, ZoneLetter: z.letter()}
+	point, err := c.ToLatLong()
+	if err != nil {
+		return latlong.Coordinate{}, err
+	}
+	// Zones 1 and 60 reach across the antimeridian
+	return point.Normalize(), nil
+}
@@ -0,0 +1,202 @@
+// Package webmercator converts between latitude/longitude and the Web
+// Mercator projection (EPSG:3857) used by online maps, both in projected
+// meters and as slippy map tiles
+//
+// Web Mercator treats the earth as a sphere with the radius of the
+// WGS84 equator and cuts the map off at about 85.05°N and S, where it
+// becomes square. At zoom level z the square is split into 2^z by 2^z
+// tiles of TileSize pixels, numbered from the top left, and named
+// "z/x/y" as in the paths of a tile cache.
+//
+// Reference for Web Mercator can be found here: https://en.wikipedia.org/wiki/Web_Mercator_projection
+// and for tile names here: https://wiki.openstreetmap.org/wiki/Slippy_map_tilenames
+package webmercator
+
+import (
+	"errors"
+	"fmt"
+	"latlong"
+	"math"
+)
+
+// Radius of the sphere projected, the WGS84 semi-major axis in meters
+const radius = 6378137
+
+// TileSize is the width and height of a tile in pixels
+const TileSize = 256
+
+// MaxZoom is the highest zoom level, where a tile is a few centimeters
+// across
+const MaxZoom = 30
+
+// MaxLatitude is the latitude of the top edge of the map, and the
+// negative of the bottom edge, in degrees
+var MaxLatitude = inverseMercator(math.Pi)
+
+// Point is a position on the Web Mercator map, in meters east and
+// north of where the equator meets the prime meridian
+type Point struct {
+	X, Y float64
+}
+
+// ToPoint projects a LatLonger onto the Web Mercator map. Latitudes
+// beyond MaxLatitude are clamped to the edge of the map.
+func ToPoint(point latlong.LatLonger) Point {
+	return Point{
+		X: radius * rad(point.Lon()),
+		Y: radius * mercator(point.Lat()),
+	}
+}
+
+// Distance north of the equator on a Mercator map of the unit sphere,
+// with latitudes clamped to MaxLatitude. This is the usual
+// ln(tan(π/4 + φ/2)), written in a form that is exactly 0 at the
+// equator.
+func mercator(lat float64) float64 {
+	lat = math.Max(-MaxLatitude, math.Min(MaxLatitude, lat))
+	return math.Asinh(math.Tan(rad(lat)))
+}
+
+// Inverse of mercator
+func inverseMercator(y float64) float64 {
+	return deg(math.Atan(math.Sinh(y)))
+}
+
+// ToLatLong finds the latitude and longitude of a point on the map
+func (p Point) ToLatLong() latlong.Coordinate {
+	return latlong.Coordinate{
+		Latitude:  inverseMercator(p.Y / radius),
+		Longitude: deg(p.X / radius),
+	}
+}
+
+func (p Point) Lat() float64 {
+	return p.ToLatLong().Latitude
+}
+
+func (p Point) Lon() float64 {
+	return p.ToLatLong().Longitude
+}
+
+// Tile is a slippy map tile. Tiles are comparable, so they can be used
+// as map keys, e.g. to count the points that fall in each tile.
+type Tile struct {
+	Zoom, X, Y int
+}
+
+// Number of tiles across the map at a zoom level
+func tiles(zoom int) int {
+	return 1 << uint(zoom)
+}
+
+// Check that the tile is on the map
+func (t Tile) check() error {
+	if !(0 <= t.Zoom && t.Zoom <= MaxZoom) {
+		return errors.New(fmt.Sprintf("Zoom level %d is not between 0 and %d", t.Zoom, MaxZoom))
+	}
+	if n := tiles(t.Zoom); !(0 <= t.X && t.X < n && 0 <= t.Y && t.Y < n) {
+		return errors.New(fmt.Sprintf("Tile %s is off the map", t))
+	}
+	return nil
+}
+
+// String names the tile as "z/x/y"
+func (t Tile) String() string {
+	return fmt.Sprintf("%d/%d/%d", t.Zoom, t.X, t.Y)
+}
+
+// ParseTile reads a tile named as "z/x/y"
+func ParseTile(s string) (Tile, error) {
+	var t Tile
+	var rest string
+	if n, _ := fmt.Sscanf(s, "%d/%d/%d%s", &t.Zoom, &t.X, &t.Y, &rest); n != 3 {
+		return Tile{}, errors.New(fmt.Sprintf("Tile '%s' is not of the form z/x/y", s))
+	}
+	if err := t.check(); err != nil {
+		return Tile{}, err
+	}
+	return t, nil
+}
+
+// Bounds of the tile
+func (t Tile) Bounds() latlong.BoundingBox {
+	nw := Position{Tile: t}.ToLatLong()
+	se := Position{Tile: t, PixelX: TileSize, PixelY: TileSize}.ToLatLong()
+	return latlong.BoundingBox{South: se.Latitude, North: nw.Latitude, West: nw.Longitude, East: se.Longitude}
+}
+
+// Position is a position on a tile, in pixels right and down from its
+// top left corner
+type Position struct {
+	Tile
+	PixelX, PixelY float64
+}
+
+// String names the tile and the pixel on it, e.g. "10/511/340 (12.5, 200.0)"
+func (p Position) String() string {
+	return fmt.Sprintf("%s (%.1f, %.1f)", p.Tile, p.PixelX, p.PixelY)
+}
+
+// ToPosition finds the tile at the given zoom level that holds a
+// LatLonger, and where on the tile it falls. Longitude 180 wraps
+// around to the left edge of the map, and latitudes beyond
+// MaxLatitude are clamped to the top or bottom edge.
+func ToPosition(point latlong.LatLonger, zoom int) (Position, error) {
+	if !(0 <= zoom && zoom <= MaxZoom) {
+		return Position{}, errors.New(fmt.Sprintf("Zoom level %d is not between 0 and %d", zoom, MaxZoom))
+	}
+
+	// Pixels right and down from the top left of the whole map, worked
+	// out in degrees so that tile edges fall exactly where they should
+	n := tiles(zoom)
+	size := float64(n) * TileSize
+	x := (point.Lon() + 180) / 360 * size
+	y := (1 - mercator(point.Lat())/math.Pi) / 2 * size
+
+	pos := Position{Tile: Tile{Zoom: zoom}}
+	pos.X = int(math.Floor(x / TileSize))
+	pos.PixelX = x - float64(pos.X)*TileSize
+	pos.X = (pos.X%n + n) % n
+	pos.Y = int(math.Floor(y / TileSize))
+	if pos.Y >= n {
+		pos.Y = n - 1 // Bottom edge of the map
+	}
+	pos.PixelY = y - float64(pos.Y)*TileSize
+	return pos, nil
+}
+
+// ToTile finds the tile at the given zoom level that holds a LatLonger
+func ToTile(point latlong.LatLonger, zoom int) (Tile, error) {
+	pos, err := ToPosition(point, zoom)
+	return pos.Tile, err
+}
+
+// ToLatLong finds the latitude and longitude of a position on a tile
+func (p Position) ToLatLong() latlong.Coordinate {
+	size := float64(tiles(p.Zoom)) * TileSize
+	x := float64(p.X)*TileSize + p.PixelX
+	y := float64(p.Y)*TileSize + p.PixelY
+	return latlong.Coordinate{
+		Latitude:  inverseMercator(math.Pi * (1 - 2*y/size)),
+		Longitude: x/size*360 - 180,
+	}
+}
+
+// ToPoint finds the position on the Web Mercator map in meters
+func (p Position) ToPoint() Point {
+	return ToPoint(p.ToLatLong())
+}
+
+func (p Position) Lat() float64 {
+	return p.ToLatLong().Latitude
+}
+
+func (p Position) Lon() float64 {
+	return p.ToLatLong().Longitude
+}
+
+// Convert angle in radians to angle in degrees
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Convert angle in degrees to angle in radians
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
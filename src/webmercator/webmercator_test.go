@@ -0,0 +1,107 @@
+package webmercator
+
+import (
+	"latlong"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const (
+	// Maximum difference between floating point values
+	closeEnough = 1e-9
+)
+
+// Check the edges of the map and tiles of known places.
+func TestKnown(t *testing.T) {
+	corner := ToPoint(latlong.Coordinate{Latitude: 90, Longitude: 180})
+	if math.Abs(corner.X-20037508.342789244) > 1e-6 || math.Abs(corner.Y-20037508.342789244) > 1e-6 {
+		t.Errorf("Top right corner of the map is %v", corner)
+	}
+	if d := math.Abs(MaxLatitude - 85.0511287798); d > 1e-9 {
+		t.Errorf("MaxLatitude is %v", MaxLatitude)
+	}
+
+	cases := []struct {
+		point latlong.Coordinate
+		zoom  int
+		tile  string
+	}{
+		{latlong.Coordinate{Latitude: 0, Longitude: 0}, 0, "0/0/0"},
+		{latlong.Coordinate{Latitude: 0, Longitude: 0}, 1, "1/1/1"},
+		{latlong.Coordinate{Latitude: 51.5074, Longitude: -0.1278}, 10, "10/511/340"},     // London
+		{latlong.Coordinate{Latitude: -33.8688, Longitude: 151.2093}, 12, "12/3768/2457"}, // Sydney
+		{latlong.Coordinate{Latitude: 10, Longitude: 180}, 3, "3/0/3"},
+		{latlong.Coordinate{Latitude: -90, Longitude: 0}, 2, "2/2/3"},
+	}
+	for _, c := range cases {
+		tile, err := ToTile(c.point, c.zoom)
+		if err != nil {
+			t.Errorf("ToTile(%v, %d) failed: %s", c.point, c.zoom, err)
+			continue
+		}
+		if tile.String() != c.tile {
+			t.Errorf("ToTile(%v, %d) was %s, wanted %s", c.point, c.zoom, tile, c.tile)
+		}
+	}
+
+	if _, err := ToTile(latlong.Coordinate{}, MaxZoom+1); err == nil {
+		t.Errorf("Zoom level %d was accepted", MaxZoom+1)
+	}
+}
+
+// Generate 100,000 random lat/long coordinates on the map, convert them
+// to meters and to positions on tiles of random zoom levels, convert
+// them back, and assert that we got something close enough to the
+// original.
+func TestRandPoints(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		want := latlong.Coordinate{
+			Latitude:  -MaxLatitude + rand.Float64()*2*MaxLatitude,
+			Longitude: -180 + rand.Float64()*360,
+		}
+
+		got := ToPoint(want).ToLatLong()
+		if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+			t.Fatalf("Difference in latitude (%g) outside of acceptable range (%g)", d, closeEnough)
+		}
+		if d := math.Abs(want.Longitude - got.Longitude); d > closeEnough {
+			t.Fatalf("Difference in longitude (%g) outside of acceptable range (%g)", d, closeEnough)
+		}
+
+		zoom := rand.Intn(MaxZoom + 1)
+		pos, err := ToPosition(want, zoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pos.check(); err != nil || pos.PixelX < 0 || pos.PixelX > TileSize || pos.PixelY < 0 || pos.PixelY > TileSize {
+			t.Fatalf("%v is at %s", want, pos)
+		}
+		got = pos.ToLatLong()
+		if d := math.Abs(want.Latitude - got.Latitude); d > closeEnough {
+			t.Fatalf("Difference in latitude (%g) outside of acceptable range (%g)", d, closeEnough)
+		}
+		if d := math.Abs(want.Longitude - got.Longitude); d > closeEnough {
+			t.Fatalf("Difference in longitude (%g) outside of acceptable range (%g)", d, closeEnough)
+		}
+		if !pos.Bounds().Contains(want) {
+			t.Fatalf("%v is outside the bounds of its tile %s, %v", want, pos.Tile, pos.Bounds())
+		}
+	}
+}
+
+// Check that tile names parse back to the same tile, and that bad names
+// are rejected.
+func TestParseTile(t *testing.T) {
+	want := Tile{Zoom: 12, X: 3768, Y: 2457}
+	if got, err := ParseTile(want.String()); err != nil || got != want {
+		t.Errorf("ParseTile(%s) was %v, %v", want, got, err)
+	}
+
+	bad := []string{"", "12/3768", "12/3768/2457/1", "12/3768/2457.5", "-1/0/0", "31/0/0", "2/4/0", "2/0/-1", "a/b/c"}
+	for _, s := range bad {
+		if got, err := ParseTile(s); err == nil {
+			t.Errorf("ParseTile(%s) was %v, wanted an error", s, got)
+		}
+	}
+}